@@ -14,6 +14,7 @@ import (
 //Endpoints:
 //GetAnimations
 //UploadPalette
+//UpdateWeight
 //DeletePalette
 
 type animationsResponse struct {
@@ -21,11 +22,12 @@ type animationsResponse struct {
 }
 
 type animationHeader struct {
-	Id            uint32 `json:"id"`
-	Name          string `json:"name"`
-	ThumbnailPath string `json:"thumbnail"`
-	Mood          string `json:"mood"`
-	IsNsfw        bool   `json:"nsfw"`
+	Id            uint32  `json:"id"`
+	Name          string  `json:"name"`
+	ThumbnailPath string  `json:"thumbnail"`
+	Mood          string  `json:"mood"`
+	IsNsfw        bool    `json:"nsfw"`
+	Weight        float64 `json:"weight"` //Relative chance of being picked by auto mode, 0 for the default weight
 }
 
 type animationUploaded struct {
@@ -47,6 +49,7 @@ func (fixture Fixture) HandleAnimationsGetAllApi(w http.ResponseWriter, r *http.
 			fmt.Sprintf("/static/thumbs/%d.png", animation.Id),
 			getMoodStr(animation.Mood),
 			animation.IsNsfw,
+			animation.GetWeight(),
 		})
 	}
 
@@ -76,6 +79,9 @@ func (fixture Fixture) HandleSingleAnimationApi(w http.ResponseWriter, r *http.R
 	case "POST":
 		fixture.handleAnimationImportApi(w, r)
 		break
+	case "PUT":
+		fixture.handleAnimationWeightApi(w, r)
+		break
 	case "DELETE":
 		fixture.handleAnimationDeleteApi(w, r)
 		break
@@ -150,6 +156,32 @@ func (fixture Fixture) handleAnimationImportApi(w http.ResponseWriter, r *http.R
 	}
 }
 
+func (fixture Fixture) handleAnimationWeightApi(w http.ResponseWriter, r *http.Request) {
+	var id Display.AnimationId
+	idStr := r.FormValue("id")
+	if idStr != "" {
+		tempId, err := strconv.ParseInt(idStr, 10, 33)
+		if err != nil || tempId < 0 {
+			http.Error(w, "Error parsing ID.", http.StatusBadRequest)
+			return
+		}
+		id = Display.AnimationId(tempId)
+	} else {
+		http.Error(w, "ID not specified.", http.StatusBadRequest)
+		return
+	}
+
+	weight, err := strconv.ParseFloat(r.FormValue("weight"), 64)
+	if err != nil || !Lightshow.ValidateWeight(weight) {
+		http.Error(w, fmt.Sprintf("The weight must be between 0 and %g.", Lightshow.MaxWeight), http.StatusBadRequest)
+		return
+	}
+
+	if err := fixture.Data.Visuals.GetAnimations().SetWeight(id, weight); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
 func (fixture Fixture) handleAnimationDeleteApi(w http.ResponseWriter, r *http.Request) {
 	var id Display.AnimationId
 	idStr := r.FormValue("id")
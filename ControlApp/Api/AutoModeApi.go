@@ -1,11 +1,9 @@
 package Api
 
 import (
-	"ControlApp/Infrastructure"
 	"ControlApp/Lightshow"
 	"encoding/json"
 	"fmt"
-	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -21,6 +19,10 @@ type AutoModeConfig struct {
 	StrobeFrequency            uint16           `json:"strobeFrequency"`
 	FlashTargetBrightness      byte             `json:"brightnessFlashBrightness"`
 	FlashHueShift              byte             `json:"hueFlashShift"`
+	IndependentBoxis           bool             `json:"independentBoxis"` //Whether each Boxi may run its own lighting mode
+	PhraseAligned              bool             `json:"phraseAligned"`    //Whether switches only happen at the start of a phrase
+	BeatsPerBar                int              `json:"beatsPerBar"`
+	BarsPerPhrase              int              `json:"barsPerPhrase"`
 	MinTimeBetweenBeatsMs      uint16           `json:"minTimeBetweenBeats"`
 	LightingCalmModeBoringSec  uint16           `json:"timeBeforeLightingBoring"`  //How long it takes until calm lighting is boring
 	AnimationCalmModeBoringSec uint16           `json:"timeBeforeAnimationBoring"` //How long it takes until a calm animation is boring
@@ -53,9 +55,9 @@ func (fixture Fixture) HandleChangeAutoModeMoodApi(w http.ResponseWriter, r *htt
 		}
 		valueNr = uint32(tempId)
 	}
-	configuration := fixture.Data.Visuals.GetConfiguration()
-	configuration.Mood = Lightshow.LightingMood(valueNr)
-	fixture.Data.Visuals.StoreConfiguration(true)
+	fixture.Data.Visuals.UpdateConfiguration(func(configuration *Lightshow.AutoModeConfiguration) {
+		configuration.Mood = Lightshow.LightingMood(valueNr)
+	}, true)
 }
 
 func (fixture Fixture) HandleChangeAutoModeNsfwApi(w http.ResponseWriter, r *http.Request) {
@@ -67,9 +69,9 @@ func (fixture Fixture) HandleChangeAutoModeNsfwApi(w http.ResponseWriter, r *htt
 
 	//Get animation ID
 	value := r.FormValue("value") == "true" || r.FormValue("value") == "1"
-	configuration := fixture.Data.Visuals.GetConfiguration()
-	configuration.AllowNsfw = value
-	fixture.Data.Visuals.StoreConfiguration(true)
+	fixture.Data.Visuals.UpdateConfiguration(func(configuration *Lightshow.AutoModeConfiguration) {
+		configuration.AllowNsfw = value
+	}, true)
 }
 
 func (fixture Fixture) HandleChangeAutoModeConfigApi(w http.ResponseWriter, r *http.Request) {
@@ -86,24 +88,33 @@ func (fixture Fixture) HandleChangeAutoModeConfigApi(w http.ResponseWriter, r *h
 		return
 	}
 
-	configuration := fixture.Data.Visuals.GetConfiguration()
-	configuration.StrobeChance = data.StrobeChance
-	configuration.HueShiftChance = data.HueShiftChance
-	configuration.FadeToColorCycles = uint16(math.Round(float64(data.FadeToColorMs) * Infrastructure.FadeDurationMsToCycles))
-	configuration.PaletteFadeCycles = uint16(math.Round(float64(data.PaletteFadeMs) * Infrastructure.FadeDurationMsToCycles))
-	configuration.FlashFadeoutSpeed = data.FlashFadeoutSpeed
-	configuration.HueFlashFadeoutSpeed = data.HueFlashFadeoutSpeed
-	configuration.StrobeFrequency = uint16(math.Round(Infrastructure.StrobeFrequencyMultiplier / float64(data.StrobeFrequency)))
-	configuration.FlashTargetBrightness = byte(math.Round(float64(data.FlashTargetBrightness) / 100 * 255))
-	configuration.FlashHueShift = data.FlashHueShift
-	configuration.MinTimeBetweenBeats = time.Duration(data.MinTimeBetweenBeatsMs) * time.Millisecond
-	configuration.LightingCalmModeBoring = time.Duration(data.LightingCalmModeBoringSec) * time.Second
-	configuration.AnimationCalmModeBoring = time.Duration(data.AnimationCalmModeBoringSec) * time.Second
-	configuration.LightingModeTiming[Lightshow.Rhythmic] = getConstraint(data.RhythmicLightingTiming)
-	configuration.LightingModeTiming[Lightshow.Frantic] = getConstraint(data.FranticLightingTiming)
-	configuration.AnimationModeTiming[Lightshow.Rhythmic] = getConstraint(data.RhythmicAnimationsTiming)
-	configuration.AnimationModeTiming[Lightshow.Frantic] = getConstraint(data.FranticAnimationsTiming)
-	fixture.Data.Visuals.StoreConfiguration(false)
+	if data.BeatsPerBar < 1 || data.BarsPerPhrase < 1 {
+		http.Error(w, "Bars and phrases need at least one beat.", http.StatusBadRequest)
+		return
+	}
+
+	fixture.Data.Visuals.UpdateConfiguration(func(configuration *Lightshow.AutoModeConfiguration) {
+		configuration.StrobeChance = data.StrobeChance
+		configuration.HueShiftChance = data.HueShiftChance
+		configuration.FadeToColorCycles = uint16(Lightshow.FadeDurationToCycles(time.Duration(data.FadeToColorMs) * time.Millisecond))
+		configuration.PaletteFadeCycles = uint16(Lightshow.FadeDurationToCycles(time.Duration(data.PaletteFadeMs) * time.Millisecond))
+		configuration.FlashFadeoutSpeed = data.FlashFadeoutSpeed
+		configuration.HueFlashFadeoutSpeed = data.HueFlashFadeoutSpeed
+		configuration.StrobeFrequency = uint16(Lightshow.StrobeFrequencyToSpeed(float64(data.StrobeFrequency)))
+		configuration.FlashTargetBrightness = Lightshow.PercentToByte(int(data.FlashTargetBrightness))
+		configuration.FlashHueShift = data.FlashHueShift
+		configuration.IndependentBoxis = data.IndependentBoxis
+		configuration.PhraseAligned = data.PhraseAligned
+		configuration.BeatsPerBar = data.BeatsPerBar
+		configuration.BarsPerPhrase = data.BarsPerPhrase
+		configuration.MinTimeBetweenBeats = time.Duration(data.MinTimeBetweenBeatsMs) * time.Millisecond
+		configuration.LightingCalmModeBoring = time.Duration(data.LightingCalmModeBoringSec) * time.Second
+		configuration.AnimationCalmModeBoring = time.Duration(data.AnimationCalmModeBoringSec) * time.Second
+		configuration.LightingModeTiming[Lightshow.Rhythmic] = getConstraint(data.RhythmicLightingTiming)
+		configuration.LightingModeTiming[Lightshow.Frantic] = getConstraint(data.FranticLightingTiming)
+		configuration.AnimationModeTiming[Lightshow.Rhythmic] = getConstraint(data.RhythmicAnimationsTiming)
+		configuration.AnimationModeTiming[Lightshow.Frantic] = getConstraint(data.FranticAnimationsTiming)
+	}, false)
 }
 
 func getConstraint(constraint TimingConstraint) Lightshow.TimingConstraint {
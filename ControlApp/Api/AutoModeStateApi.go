@@ -0,0 +1,118 @@
+package Api
+
+import (
+	"ControlApp/Display"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type AutoModeState struct {
+	Running              bool                `json:"running"`
+	Paused               bool                `json:"paused"`
+	Phase                string              `json:"phase"`
+	WasInCalmMode        bool                `json:"wasInCalmMode"`
+	Now                  time.Time           `json:"now"`      //Time of the server, to relate the other times to
+	LastBeat             *time.Time          `json:"lastBeat"` //Null if no beat was detected yet
+	LightingBeatsLeft    int                 `json:"lightingBeatsLeft"`
+	AnimationBeatsLeft   int                 `json:"animationBeatsLeft"`
+	LightingDeadline     *time.Time          `json:"lightingDeadline"`     //When the lighting calms down without beats
+	AnimationDeadline    *time.Time          `json:"animationDeadline"`    //When the animations calm down without beats
+	LightingBoringInSec  *float64            `json:"lightingBoringInSec"`  //Null if the lighting isn't calm
+	AnimationBoringInSec *float64            `json:"animationBoringInSec"` //Null if the animations aren't calm
+	Lighting             *AutoModeLighting   `json:"lighting"`             //Null if no lighting was picked yet
+	Animations           *AutoModeAnimations `json:"animations"`           //Null if no animations were picked yet
+}
+
+type AutoModeLighting struct {
+	Palette   string    `json:"palette"`
+	Modes     [2]string `json:"modes"` //Of Boxi 1 and Boxi 2
+	Character string    `json:"character"`
+}
+
+type AutoModeAnimations struct {
+	Animations []AutoModeAnimation `json:"animations"`
+	Character  string              `json:"character"`
+}
+
+type AutoModeAnimation struct {
+	Id       uint32 `json:"id"`
+	Name     string `json:"name"`
+	Displays []int  `json:"displays"` //Numbered 1 to 4
+}
+
+func (fixture Fixture) HandleAutoModeStateApi(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := fixture.Data.Visuals.GetAutoMode().Status()
+	result := AutoModeState{
+		Running:              status.Running,
+		Paused:               status.Paused,
+		Phase:                status.Phase.String(),
+		WasInCalmMode:        status.WasInCalmMode,
+		Now:                  time.Now(),
+		LastBeat:             status.LastBeat,
+		LightingBeatsLeft:    status.LightingBeatsLeft,
+		AnimationBeatsLeft:   status.AnimationBeatsLeft,
+		LightingDeadline:     status.LightingDeadline,
+		AnimationDeadline:    status.AnimationDeadline,
+		LightingBoringInSec:  toSeconds(status.LightingBoringIn),
+		AnimationBoringInSec: toSeconds(status.AnimationBoringIn),
+	}
+
+	if status.Lighting != nil {
+		result.Lighting = &AutoModeLighting{
+			Palette:   status.Lighting.Palette,
+			Modes:     [2]string{status.Lighting.Modes[0].String(), status.Lighting.Modes[1].String()},
+			Character: status.Lighting.Character.String(),
+		}
+	}
+
+	if status.Animations != nil {
+		animations := AutoModeAnimations{
+			Animations: make([]AutoModeAnimation, 0, len(status.Animations.Animations)),
+			Character:  status.Animations.Character.String(),
+		}
+		for _, instruction := range status.Animations.Animations {
+			animation := AutoModeAnimation{Id: uint32(instruction.Animation), Displays: make([]int, 0)}
+			if ok, details := fixture.Data.Visuals.GetAnimations().GetById(instruction.Animation); ok {
+				animation.Name = details.Name
+			}
+			for _, displays := range instruction.Displays {
+				animation.Displays = append(animation.Displays, getDisplayNumbers(displays)...)
+			}
+			animations.Animations = append(animations.Animations, animation)
+		}
+		result.Animations = &animations
+	}
+
+	//Encode data
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func toSeconds(duration *time.Duration) *float64 {
+	if duration == nil {
+		return nil
+	}
+
+	seconds := duration.Seconds()
+	return &seconds
+}
+
+// getDisplayNumbers returns the numbers of the displays in the set, from 1 to 4.
+func getDisplayNumbers(displays Display.ServerDisplay) []int {
+	var numbers []int
+	for i := 0; i < 4; i++ {
+		if displays&(1<<i) != 0 {
+			numbers = append(numbers, i+1)
+		}
+	}
+
+	return numbers
+}
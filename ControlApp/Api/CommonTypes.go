@@ -1,5 +1,7 @@
 package Api
 
+import "ControlApp/BoxiBus"
+
 type Color struct {
 	R  int
 	G  int
@@ -17,3 +19,25 @@ func isColorValid(color Color) bool {
 		color.A >= 0 && color.A <= 0xFF &&
 		color.UV >= 0 && color.UV <= 0xFF
 }
+
+func (color Color) toBusColor() BoxiBus.Color {
+	return BoxiBus.Color{
+		Red:         byte(color.R),
+		Green:       byte(color.G),
+		Blue:        byte(color.B),
+		White:       byte(color.W),
+		Amber:       byte(color.A),
+		UltraViolet: byte(color.UV),
+	}
+}
+
+func fromBusColor(color BoxiBus.Color) Color {
+	return Color{
+		R:  int(color.Red),
+		G:  int(color.Green),
+		B:  int(color.Blue),
+		W:  int(color.White),
+		A:  int(color.Amber),
+		UV: int(color.UltraViolet),
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"ControlApp/Display"
 	"ControlApp/Infrastructure"
 	"ControlApp/Lightshow"
+	"sync"
 )
 
 type DataContainer struct {
@@ -12,6 +13,10 @@ type DataContainer struct {
 	OverrideLightingCurrent  LightingInstructionTotal
 	OverrideAnimationCurrent ScreenOverrideAnimationProperties
 	OverrideTextsCurrent     ScreenOverrideTextProperties
+	DeskInControl            bool //Whether a lighting desk overrides the lighting
+	Presets                  *PresetManager
+	lightingLock             *sync.Mutex //Guards the lighting override between the API and the lighting desk
+	screensLock              *sync.Mutex //Guards the screen overrides between the API and presets
 }
 
 func CreateDataContainer(hardware Infrastructure.HardwareInterface, visuals *Lightshow.VisualManager) *DataContainer {
@@ -26,8 +31,10 @@ func CreateDataContainer(hardware Infrastructure.HardwareInterface, visuals *Lig
 			DurationMs:       2000,
 			PaletteShift:     0,
 			Speed:            40,
-			TargetBrightness: 15,
+			TargetBrightness: 6,
 			FrequencyHz:      12,
+			HueOffset:        180,
+			Saturation:       100,
 		},
 		ScreenOverrideAnimationProperties{
 			Animations: []ScreenOverrideAnimationInstance{
@@ -47,7 +54,19 @@ func CreateDataContainer(hardware Infrastructure.HardwareInterface, visuals *Lig
 				{ScreenIndex: Display.Boxi2D2, Text: " "},
 			},
 		},
+		false,
+		LoadPresets(),
+		&sync.Mutex{},
+		&sync.Mutex{},
 	}
 
 	return &result
 }
+
+// GetLightingOverride returns a copy of the lighting override and whether a lighting desk overrides it.
+func (data *DataContainer) GetLightingOverride() (LightingInstructionTotal, bool) {
+	data.lightingLock.Lock()
+	defer data.lightingLock.Unlock()
+
+	return data.OverrideLightingCurrent.clone(), data.DeskInControl
+}
@@ -0,0 +1,58 @@
+package Api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type HardwareConnectionState struct {
+	Device          string             `json:"device"`
+	Connected       bool               `json:"connected"`
+	Since           time.Time          `json:"since"` //When the connection was last established or lost
+	ProtocolVersion byte               `json:"protocolVersion"`
+	Reconnects      int                `json:"reconnects"`
+	LastError       string             `json:"lastError"`
+	Telemetry       *HardwareTelemetry `json:"telemetry"` //Nil if the firmware doesn't send telemetry, like lightshow_v3
+}
+
+type HardwareTelemetry struct {
+	LightingConfirmed bool      `json:"lightingConfirmed"` //Whether the Arduino confirmed that the last lighting took effect
+	StatusCode        byte      `json:"statusCode"`
+	Brightness        float64   `json:"brightness"` //Set by the potentiometer, between 0 and 1
+	LastBeat          time.Time `json:"lastBeat"`
+}
+
+func (fixture Fixture) HandleHardwareConnectionApi(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := fixture.Data.Hardware.GetConnectionState()
+	result := HardwareConnectionState{
+		Device:          state.Device,
+		Connected:       state.Connected,
+		Since:           state.Since,
+		ProtocolVersion: state.ProtocolVersion,
+		Reconnects:      state.Reconnects,
+	}
+	if state.LastError != nil {
+		result.LastError = state.LastError.Error()
+	}
+	if state.HasTelemetry() {
+		telemetry := fixture.Data.Hardware.GetTelemetry()
+		result.Telemetry = &HardwareTelemetry{
+			LightingConfirmed: fixture.Data.Visuals.IsLightingConfirmed(),
+			StatusCode:        byte(telemetry.StatusCode),
+			Brightness:        telemetry.Brightness,
+			LastBeat:          telemetry.LastBeat,
+		}
+	}
+
+	//Encode data
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
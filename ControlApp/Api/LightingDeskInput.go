@@ -0,0 +1,157 @@
+package Api
+
+import (
+	"ControlApp/Dmx"
+	"ControlApp/Lightshow"
+	"fmt"
+	"log"
+	"time"
+)
+
+// The channels of the lighting desk, relative to the configured start address
+const (
+	deskChannelMode       = 0  //0 hands control back to the Boxis, otherwise the lighting mode + 1
+	deskChannelOnBeat     = 1  //Apply lighting changes on the beat from 128
+	deskChannelColorA     = 2  //RGBWA UV of Boxi 1, one channel each
+	deskChannelColorB     = 8  //RGBWA UV of Boxi 2, one channel each
+	deskChannelPalette    = 14 //Index into the palettes sorted by their ID
+	deskChannelShift      = 15
+	deskChannelDuration   = 16 //In steps of 100 ms
+	deskChannelSpeed      = 17 //Fade out speed of the flash modes
+	deskChannelBrightness = 18 //Target or minimum brightness of the mode, 255 is 100 %
+	deskChannelFrequency  = 19 //Strobe frequency in Hz
+	deskChannelHueOffset  = 20 //256 is a full rotation
+	deskChannelSaturation = 21 //255 is 100 %
+	deskChannelCount      = 22
+)
+
+const defaultDeskTimeout = 5 * time.Second
+
+// StartLightingDeskInput lets a lighting desk take over the lighting while it sends DMX.
+// The desk has priority over overrides from the web interface, which take effect again when the desk
+// hands back control or goes quiet.
+func (fixture Fixture) StartLightingDeskInput(config Dmx.InputConfiguration) error {
+	if config.Address < 1 || int(config.Address)+deskChannelCount-1 > Dmx.UniverseSize {
+		return fmt.Errorf("the %d desk channels starting at %d don't fit into the universe", deskChannelCount, config.Address)
+	}
+
+	universes, err := Dmx.ListenForInput(config)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(config.TimeoutSec) * time.Second
+	if timeout == 0 {
+		timeout = defaultDeskTimeout
+	}
+
+	go fixture.followLightingDesk(universes, int(config.Address)-1, timeout)
+	return nil
+}
+
+func (fixture Fixture) followLightingDesk(universes <-chan Dmx.Universe, offset int, timeout time.Duration) {
+	var current LightingInstructionTotal
+	quiet := time.NewTimer(timeout)
+	quiet.Stop()
+
+	for {
+		select {
+		case universe := <-universes:
+			channels := universe[offset : offset+deskChannelCount]
+			if channels[deskChannelMode] == 0 {
+				quiet.Stop()
+				current = LightingInstructionTotal{}
+				fixture.releaseLightingDesk("handed back control")
+				continue
+			}
+
+			quiet.Reset(timeout)
+			data := getDeskInstruction(channels, fixture.Data.Visuals.GetPalettes())
+			if data == current {
+				continue
+			}
+
+			current = data
+			fixture.applyLightingDesk(data)
+		case <-quiet.C:
+			current = LightingInstructionTotal{}
+			fixture.releaseLightingDesk("went quiet")
+		}
+	}
+}
+
+func (fixture Fixture) applyLightingDesk(data LightingInstructionTotal) {
+	fixture.Data.lightingLock.Lock()
+	defer fixture.Data.lightingLock.Unlock()
+
+	instruction, err := data.createLightingInstruction(fixture.Data.Visuals)
+	if err != nil {
+		//Keep the lighting until the desk sends something valid
+		log.Printf("Lighting desk sent invalid lighting: %s", err)
+		return
+	}
+
+	if !fixture.Data.DeskInControl {
+		log.Println("Lighting desk took over the lighting")
+		fixture.Data.DeskInControl = true
+	}
+
+	fixture.Data.Visuals.SetLightingOverwrite(instruction)
+}
+
+// releaseLightingDesk restores the override of the web interface, or auto mode if there is none.
+func (fixture Fixture) releaseLightingDesk(reason string) {
+	fixture.Data.lightingLock.Lock()
+	defer fixture.Data.lightingLock.Unlock()
+
+	if !fixture.Data.DeskInControl {
+		return
+	}
+
+	log.Printf("Lighting desk %s", reason)
+	fixture.Data.DeskInControl = false
+
+	instruction, err := fixture.Data.OverrideLightingCurrent.createLightingInstruction(fixture.Data.Visuals)
+	if err != nil {
+		log.Printf("Lighting override can't be restored, returning to auto mode: %s", err)
+		instruction = nil
+	}
+
+	fixture.Data.Visuals.SetLightingOverwrite(instruction)
+}
+
+// getDeskInstruction converts the desk channels into a lighting override.
+func getDeskInstruction(channels []byte, palettes *Lightshow.PaletteManager) LightingInstructionTotal {
+	getColor := func(first int) Color {
+		return Color{
+			R:  int(channels[first]),
+			G:  int(channels[first+1]),
+			B:  int(channels[first+2]),
+			W:  int(channels[first+3]),
+			A:  int(channels[first+4]),
+			UV: int(channels[first+5]),
+		}
+	}
+
+	data := LightingInstructionTotal{
+		Enable:           true,
+		ApplyOnBeat:      channels[deskChannelOnBeat] >= 128,
+		Mode:             int(channels[deskChannelMode]) - 1,
+		ColorDeviceA:     getColor(deskChannelColorA),
+		ColorDeviceB:     getColor(deskChannelColorB),
+		DurationMs:       int(channels[deskChannelDuration]) * 100,
+		PaletteShift:     int(channels[deskChannelShift]),
+		Speed:            int(channels[deskChannelSpeed]),
+		TargetBrightness: Lightshow.ByteToPercent(channels[deskChannelBrightness]),
+		FrequencyHz:      int(channels[deskChannelFrequency]),
+		HueOffset:        int(channels[deskChannelHueOffset]) * 360 / 256,
+		Saturation:       Lightshow.ByteToPercent(channels[deskChannelSaturation]),
+	}
+
+	allPalettes := palettes.GetAll()
+	if len(allPalettes) > 0 {
+		data.PaletteId = allPalettes[min(int(channels[deskChannelPalette]), len(allPalettes)-1)].Id
+	}
+
+	return data
+}
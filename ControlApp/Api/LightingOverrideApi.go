@@ -2,11 +2,13 @@ package Api
 
 import (
 	"ControlApp/BoxiBus"
-	"ControlApp/Infrastructure"
 	"ControlApp/Lightshow"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 type LightingInstructionTotal struct {
@@ -19,8 +21,13 @@ type LightingInstructionTotal struct {
 	DurationMs       int    `json:"duration"`
 	PaletteShift     int    `json:"paletteShift"`
 	Speed            int    `json:"speed"`
-	TargetBrightness int    `json:"targetBrightness"`
+	TargetBrightness int    `json:"targetBrightness"` //Percent
 	FrequencyHz      int    `json:"frequency"`
+	HueOffset        int    `json:"hueOffset"`  //Degrees
+	Saturation       int    `json:"saturation"` //Percent
+
+	//Separate lighting of Boxi 2, the top level lighting is then only used for Boxi 1
+	Boxi2 *LightingInstructionTotal `json:"boxi2,omitempty"`
 }
 
 func (fixture Fixture) HandleSetLightingOverrideAutoApi(w http.ResponseWriter, r *http.Request) {
@@ -37,135 +44,180 @@ func (fixture Fixture) HandleSetLightingOverrideAutoApi(w http.ResponseWriter, r
 		return
 	}
 
+	if err := fixture.setLightingOverride(data, 0); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// setLightingOverride stores the override and applies it, the lighting fades in over the given duration
+// where the mode allows it.
+func (fixture Fixture) setLightingOverride(data LightingInstructionTotal, fade time.Duration) error {
+	fixture.Data.lightingLock.Lock()
+	defer fixture.Data.lightingLock.Unlock()
+
 	fixture.Data.OverrideLightingCurrent = data
 
-	if !data.Enable {
-		fixture.Data.Visuals.SetLightingOverwrite(nil)
-		return
+	instruction, err := data.withCrossfade(fade).createLightingInstruction(fixture.Data.Visuals)
+	if err != nil {
+		return err
 	}
 
-	if !isColorValid(data.ColorDeviceA) {
-		http.Error(w, "Color A is invalid.", http.StatusBadRequest)
-		return
+	//The override takes effect once the lighting desk hands back control
+	if fixture.Data.DeskInControl {
+		return nil
 	}
 
-	if !isColorValid(data.ColorDeviceB) {
-		http.Error(w, "Color B is invalid.", http.StatusBadRequest)
-		return
-	}
+	fixture.Data.Visuals.SetLightingOverwrite(instruction)
+	return nil
+}
 
-	color1 := BoxiBus.Color{
-		Red:         byte(data.ColorDeviceA.R),
-		Green:       byte(data.ColorDeviceA.G),
-		Blue:        byte(data.ColorDeviceA.B),
-		White:       byte(data.ColorDeviceA.W),
-		Amber:       byte(data.ColorDeviceA.A),
-		UltraViolet: byte(data.ColorDeviceA.UV),
+// withCrossfade returns the override fading into its colors over the given duration, if its mode shows static colors.
+// Other modes start right away.
+func (data LightingInstructionTotal) withCrossfade(fade time.Duration) LightingInstructionTotal {
+	if fade <= 0 {
+		return data
 	}
 
-	color2 := BoxiBus.Color{
-		Red:         byte(data.ColorDeviceB.R),
-		Green:       byte(data.ColorDeviceB.G),
-		Blue:        byte(data.ColorDeviceB.B),
-		White:       byte(data.ColorDeviceB.W),
-		Amber:       byte(data.ColorDeviceB.A),
-		UltraViolet: byte(data.ColorDeviceB.UV),
+	switch BoxiBus.LightingModeId(data.Mode) {
+	case BoxiBus.Off:
+		data.ColorDeviceA = Color{}
+		data.ColorDeviceB = Color{}
+		fallthrough
+	case BoxiBus.SetColor:
+		data.Mode = int(BoxiBus.FadeToColor)
+		data.DurationMs = int(fade.Milliseconds())
 	}
 
-	durationCycles := int(float64(data.DurationMs) * Infrastructure.FadeDurationMsToCycles)
-	if durationCycles <= 0 || durationCycles > 0xFFFF {
-		http.Error(w, "Fade duration outside of range.", http.StatusBadRequest)
-		return
+	if data.Boxi2 != nil {
+		boxi2 := data.Boxi2.withCrossfade(fade)
+		data.Boxi2 = &boxi2
 	}
 
-	success, palette := fixture.Data.Visuals.GetPalettes().GetById(data.PaletteId)
-	if !success {
-		http.Error(w, "Palette not found.", http.StatusBadRequest)
-		return
+	return data
+}
+
+// createLightingInstruction validates the override, it returns nil if the override is disabled.
+func (data LightingInstructionTotal) createLightingInstruction(visuals *Lightshow.VisualManager) (*Lightshow.LightingInstruction, error) {
+	if !data.Enable {
+		return nil, nil
 	}
 
-	if data.PaletteShift < 0 || data.PaletteShift > 7 {
-		http.Error(w, "Palette shift outside of range.", http.StatusBadRequest)
-		return
+	palettes := visuals.GetPalettes()
+	mode, err := data.getLightingMode(palettes)
+	if err != nil {
+		return nil, err
 	}
 
-	frequency := int((1 / float64(data.FrequencyHz)) * Infrastructure.StrobeFrequencyMultiplier)
-	if frequency <= 0 || frequency > 0xF {
-		http.Error(w, "frequency outside of range.", http.StatusBadRequest)
-		return
+	if err := mode.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid lighting parameters. %s", err)
 	}
 
-	var instruction Lightshow.LightingInstruction
+	if !visuals.SupportsLightingMode(mode.Id()) {
+		return nil, fmt.Errorf("The %s mode isn't supported by the firmware of the Arduino.", mode.Id())
+	}
 
-	switch data.Mode {
-	case 0:
-		instruction = Lightshow.LightingInstruction{
-			MessageBlock: BoxiBus.CreateLightingOff(data.ApplyOnBeat),
-		}
-		break
-	case 1:
-		instruction = Lightshow.LightingInstruction{
-			MessageBlock: BoxiBus.CreateLightingSetColor(color1, color2, data.ApplyOnBeat),
-		}
-		break
-	case 2:
-		instruction = Lightshow.LightingInstruction{
-			MessageBlock: BoxiBus.CreateLightingFadeToColor(color1, color2, uint16(durationCycles), data.ApplyOnBeat),
-		}
-		break
-	case 3:
-		block, err := BoxiBus.CreateLightingPaletteFade(palette.Colors, uint16(durationCycles), byte(data.PaletteShift), data.ApplyOnBeat)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Instruction couldn't be created. %s", err), http.StatusInternalServerError)
-			return
+	instruction := Lightshow.CreateLightingInstruction(mode)
+	if data.Boxi2 != nil {
+		if !visuals.SupportsPerBoxiLighting() {
+			return nil, errors.New("Separate lighting for Boxi 2 isn't supported by the firmware of the Arduino.")
 		}
 
-		instruction = Lightshow.LightingInstruction{
-			MessageBlock: block,
-		}
-		break
-	case 4:
-		block, err := BoxiBus.CreateLightingPaletteSwitch(palette.Colors, byte(data.PaletteShift), data.ApplyOnBeat)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Instruction couldn't be created. %s", err), http.StatusInternalServerError)
-			return
-		}
+		//Both Boxis switch at the same time
+		boxi2Data := *data.Boxi2
+		boxi2Data.ApplyOnBeat = data.ApplyOnBeat
 
-		instruction = Lightshow.LightingInstruction{
-			MessageBlock: block,
-		}
-		break
-	case 5:
-		block, err := BoxiBus.CreateLightingPaletteBrightnessFlash(palette.Colors, uint16(data.Speed), byte(data.TargetBrightness), byte(data.PaletteShift), data.ApplyOnBeat)
+		boxi2Mode, err := boxi2Data.getLightingMode(palettes)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Instruction couldn't be created. %s", err), http.StatusInternalServerError)
-			return
+			return nil, fmt.Errorf("Boxi 2: %s", err)
 		}
 
-		instruction = Lightshow.LightingInstruction{
-			MessageBlock: block,
+		if err := boxi2Mode.Validate(); err != nil {
+			return nil, fmt.Errorf("Invalid lighting parameters for Boxi 2. %s", err)
 		}
-		break
-	case 6:
 
-		block, err := BoxiBus.CreateLightingPaletteHueFlash(palette.Colors, uint16(data.Speed), byte(data.PaletteShift), data.ApplyOnBeat)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Instruction couldn't be created. %s", err), http.StatusInternalServerError)
-			return
+		if !visuals.SupportsLightingMode(boxi2Mode.Id()) {
+			return nil, fmt.Errorf("The %s mode of Boxi 2 isn't supported by the firmware of the Arduino.", boxi2Mode.Id())
 		}
 
-		instruction = Lightshow.LightingInstruction{
-			MessageBlock: block,
-		}
-		break
-	case 7:
-		instruction = Lightshow.LightingInstruction{
-			MessageBlock: BoxiBus.CreateLightingStrobe(color1, uint16(frequency), 0, data.ApplyOnBeat),
+		instruction = Lightshow.CreateLightingInstructionPerBoxi(mode, boxi2Mode)
+	}
+
+	return &instruction, nil
+}
+
+// getLightingMode converts the instruction into the lighting mode it describes.
+func (data LightingInstructionTotal) getLightingMode(palettes *Lightshow.PaletteManager) (Lightshow.LightingMode, error) {
+	if !isColorValid(data.ColorDeviceA) {
+		return nil, errors.New("Color A is invalid.")
+	}
+
+	if !isColorValid(data.ColorDeviceB) {
+		return nil, errors.New("Color B is invalid.")
+	}
+
+	color1 := data.ColorDeviceA.toBusColor()
+	color2 := data.ColorDeviceB.toBusColor()
+	duration := time.Duration(data.DurationMs) * time.Millisecond
+
+	var palette []BoxiBus.Color
+	switch BoxiBus.LightingModeId(data.Mode) {
+	case BoxiBus.PaletteFade, BoxiBus.PaletteSwitch, BoxiBus.PaletteBrightnessFlash, BoxiBus.PaletteHueFlash, BoxiBus.Chase,
+		BoxiBus.Breathing:
+		success, entity := palettes.GetById(data.PaletteId)
+		if !success {
+			return nil, errors.New("Palette not found.")
 		}
-		break
+		palette = entity.Colors
+	}
+
+	switch BoxiBus.LightingModeId(data.Mode) {
+	case BoxiBus.Off:
+		return Lightshow.OffMode{ApplyOnBeat: data.ApplyOnBeat}, nil
+	case BoxiBus.SetColor:
+		return Lightshow.SetColorMode{Boxi1: color1, Boxi2: color2, ApplyOnBeat: data.ApplyOnBeat}, nil
+	case BoxiBus.FadeToColor:
+		return Lightshow.FadeToColorMode{Boxi1: color1, Boxi2: color2, Duration: duration, ApplyOnBeat: data.ApplyOnBeat}, nil
+	case BoxiBus.PaletteFade:
+		return Lightshow.PaletteFadeMode{Palette: palette, Duration: duration, Shift: data.PaletteShift, ApplyOnBeat: data.ApplyOnBeat}, nil
+	case BoxiBus.PaletteSwitch:
+		return Lightshow.PaletteSwitchMode{Palette: palette, Shift: data.PaletteShift, ApplyOnBeat: data.ApplyOnBeat}, nil
+	case BoxiBus.PaletteBrightnessFlash:
+		return Lightshow.BrightnessFlashMode{
+			Palette:          palette,
+			FadeOutSpeed:     uint16(min(max(data.Speed, 0), math.MaxUint16)),
+			TargetBrightness: data.TargetBrightness,
+			Shift:            data.PaletteShift,
+			ApplyOnBeat:      data.ApplyOnBeat,
+		}, nil
+	case BoxiBus.PaletteHueFlash:
+		return Lightshow.HueFlashMode{
+			Palette:      palette,
+			FadeOutSpeed: uint16(min(max(data.Speed, 0), math.MaxUint16)),
+			Shift:        data.PaletteShift,
+			ApplyOnBeat:  data.ApplyOnBeat,
+		}, nil
+	case BoxiBus.Strobe:
+		return Lightshow.StrobeMode{Color: color1, Frequency: float64(data.FrequencyHz), ApplyOnBeat: data.ApplyOnBeat}, nil
+	case BoxiBus.Chase:
+		return Lightshow.ChaseMode{Palette: palette, BackgroundBrightness: data.TargetBrightness, ApplyOnBeat: data.ApplyOnBeat}, nil
+	case BoxiBus.RainbowCycle:
+		return Lightshow.RainbowCycleMode{
+			Duration:    duration,
+			HueOffset:   data.HueOffset,
+			Saturation:  data.Saturation,
+			ApplyOnBeat: data.ApplyOnBeat,
+		}, nil
+	case BoxiBus.Breathing:
+		return Lightshow.BreathingMode{
+			Palette:       palette,
+			Duration:      duration,
+			MinBrightness: data.TargetBrightness,
+			Shift:         data.PaletteShift,
+			ApplyOnBeat:   data.ApplyOnBeat,
+		}, nil
 	}
 
-	fixture.Data.Visuals.SetLightingOverwrite(&instruction)
+	return nil, fmt.Errorf("Unknown lighting mode %d.", data.Mode)
 }
 
 func (fixture Fixture) HandleSetInternalLedsEnabled(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,76 @@
+package Api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const previewInterval = time.Second / 30
+
+type LightingPreview struct {
+	Boxi1 BoxiPreview `json:"boxi1"`
+	Boxi2 BoxiPreview `json:"boxi2"`
+}
+
+type BoxiPreview struct {
+	Mode  int   `json:"mode"`
+	Color Color `json:"color"`
+}
+
+// HandleLightingPreviewApi streams the colors the Boxis are showing as server-sent events.
+// An event is only sent when the colors or modes changed.
+func (fixture Fixture) HandleLightingPreviewApi(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	renderer := fixture.Data.Visuals.GetRenderer()
+	ticker := time.NewTicker(previewInterval)
+	defer ticker.Stop()
+
+	var last LightingPreview
+	first := true
+	for {
+		colors := renderer.GetColors()
+		modes := renderer.GetModes()
+		preview := LightingPreview{
+			Boxi1: BoxiPreview{Mode: int(modes[0]), Color: fromBusColor(colors[0])},
+			Boxi2: BoxiPreview{Mode: int(modes[1]), Color: fromBusColor(colors[1])},
+		}
+
+		if first || preview != last {
+			payload, err := json.Marshal(preview)
+			if err != nil {
+				return
+			}
+
+			//The client went away
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", payload); err != nil {
+				return
+			}
+			flusher.Flush()
+
+			last = preview
+			first = false
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
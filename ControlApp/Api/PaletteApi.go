@@ -30,6 +30,7 @@ type paletteType struct {
 	paletteHeader
 	Moods  []int   `json:"moods"`
 	Colors []Color `json:"colors"`
+	Weight float64 `json:"weight"` //Relative chance of being picked by auto mode, 0 for the default weight
 }
 
 type paletteCreate struct {
@@ -107,7 +108,7 @@ func (fixture Fixture) handlePaletteGetApi(w http.ResponseWriter, r *http.Reques
 		moods = append(moods, int(mood))
 	}
 
-	palette := paletteType{header, moods, colors}
+	palette := paletteType{header, moods, colors, entity.GetWeight()}
 
 	//Encode data
 	if err := json.NewEncoder(w).Encode(palette); err != nil {
@@ -129,8 +130,11 @@ func (fixture Fixture) handlePaletteCreateApi(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	palette := Lightshow.Palette{Id: id, Name: data.Name, Moods: []Lightshow.LightingMood{Lightshow.Regular}, Colors: []BoxiBus.Color{{}}}
-	fixture.Data.Visuals.GetPalettes().SetPalette(palette)
+	palette := Lightshow.Palette{Id: id, Name: data.Name, Moods: []Lightshow.LightingMood{Lightshow.Regular}, Colors: []BoxiBus.Color{{}}, Weight: Lightshow.DefaultWeight}
+	if err := fixture.Data.Visuals.GetPalettes().SetPalette(palette); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	returnData := paletteCreated{id}
 
@@ -162,6 +166,21 @@ func (fixture Fixture) handlePaletteUpdateApi(w http.ResponseWriter, r *http.Req
 		moods = append(moods, Lightshow.LightingMood(mood))
 	}
 
+	if len(data.Colors) == 0 || len(data.Colors) > BoxiBus.MaxPaletteSize {
+		http.Error(w, fmt.Sprintf("A palette must have between 1 and %d colors.", BoxiBus.MaxPaletteSize), http.StatusBadRequest)
+		return
+	}
+
+	if maxSize := fixture.Data.Hardware.GetConnectionState().MaxPaletteSize(); len(data.Colors) > maxSize {
+		http.Error(w, fmt.Sprintf("The firmware of the Arduino only supports palettes of up to %d colors.", maxSize), http.StatusBadRequest)
+		return
+	}
+
+	if !Lightshow.ValidateWeight(data.Weight) {
+		http.Error(w, fmt.Sprintf("The weight must be between 0 and %g.", Lightshow.MaxWeight), http.StatusBadRequest)
+		return
+	}
+
 	for idx, color := range data.Colors {
 		if !isColorValid(color) {
 			http.Error(w, fmt.Sprintf("Palette color %d is invalid.", idx+1), http.StatusBadRequest)
@@ -180,8 +199,10 @@ func (fixture Fixture) handlePaletteUpdateApi(w http.ResponseWriter, r *http.Req
 		colors = append(colors, internalColor)
 	}
 
-	palette := Lightshow.Palette{Id: data.Id, Name: data.Name, Moods: moods, Colors: colors}
-	fixture.Data.Visuals.GetPalettes().SetPalette(palette)
+	palette := Lightshow.Palette{Id: data.Id, Name: data.Name, Moods: moods, Colors: colors, Weight: data.Weight}
+	if err := fixture.Data.Visuals.GetPalettes().SetPalette(palette); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
 }
 
 func (fixture Fixture) handlePaletteDeleteApi(w http.ResponseWriter, r *http.Request) {
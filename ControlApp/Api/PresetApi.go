@@ -0,0 +1,150 @@
+package Api
+
+import (
+	"ControlApp/Lightshow"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+//Endpoints:
+//GetAllPresets
+//SavePreset
+//RecallPreset
+//DeletePreset
+
+const maxPresetFade = 20 * time.Second
+
+type presetAllPresets struct {
+	Presets []presetHeader `json:"presets"`
+}
+
+type presetHeader struct {
+	Name string `json:"name"`
+}
+
+func (fixture Fixture) HandlePresetGetAllApi(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	presets := make([]presetHeader, 0)
+	for _, preset := range fixture.Data.Presets.GetAll() {
+		presets = append(presets, presetHeader{preset.Name})
+	}
+
+	//Encode data
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(presetAllPresets{presets}); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (fixture Fixture) HandleSinglePresetApi(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		fixture.handlePresetSaveApi(w, r)
+	case "DELETE":
+		fixture.handlePresetDeleteApi(w, r)
+	default:
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePresetSaveApi stores the current auto mode configuration and overrides under the name, replacing a preset with the same name.
+func (fixture Fixture) handlePresetSaveApi(w http.ResponseWriter, r *http.Request) {
+	preset := fixture.capturePreset(r.FormValue("name"))
+	if err := fixture.Data.Presets.SetPreset(preset); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func (fixture Fixture) handlePresetDeleteApi(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	if exists, _ := fixture.Data.Presets.GetByName(name); !exists {
+		http.Error(w, "Preset does not exist.", http.StatusBadRequest)
+		return
+	}
+
+	fixture.Data.Presets.RemovePreset(name)
+}
+
+// HandleRecallPresetApi applies a preset. Static lighting fades in over the given time in milliseconds.
+func (fixture Fixture) HandleRecallPresetApi(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	exists, preset := fixture.Data.Presets.GetByName(r.FormValue("name"))
+	if !exists {
+		http.Error(w, "Preset does not exist.", http.StatusBadRequest)
+		return
+	}
+
+	var fade time.Duration
+	if fadeStr := r.FormValue("fade"); fadeStr != "" {
+		fadeMs, err := strconv.ParseInt(fadeStr, 10, 32)
+		fade = time.Duration(fadeMs) * time.Millisecond
+		if err != nil || fade < 0 || fade > maxPresetFade {
+			http.Error(w, fmt.Sprintf("The fade must be between 0 and %d ms.", maxPresetFade.Milliseconds()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := fixture.recallPreset(preset, fade); err != nil {
+		http.Error(w, fmt.Sprintf("Preset can't be applied. %s", err), http.StatusBadRequest)
+	}
+}
+
+// capturePreset takes a snapshot of the current auto mode configuration and overrides.
+func (fixture Fixture) capturePreset(name string) Preset {
+	preset := Preset{Name: name, AutoMode: fixture.Data.Visuals.GetConfiguration()}
+
+	fixture.Data.lightingLock.Lock()
+	preset.Lighting = fixture.Data.OverrideLightingCurrent.clone()
+	fixture.Data.lightingLock.Unlock()
+
+	fixture.Data.screensLock.Lock()
+	preset.Animations = fixture.Data.OverrideAnimationCurrent
+	preset.Animations.Animations = slices.Clone(preset.Animations.Animations)
+	preset.Texts.Texts = slices.Clone(fixture.Data.OverrideTextsCurrent.Texts)
+	fixture.Data.screensLock.Unlock()
+
+	return preset
+}
+
+// recallPreset applies the auto mode configuration and overrides of the preset. Everything is validated
+// first, so an outdated preset, e.g. one using a deleted palette, changes nothing.
+func (fixture Fixture) recallPreset(preset Preset, fade time.Duration) error {
+	if _, err := preset.Lighting.withCrossfade(fade).createLightingInstruction(fixture.Data.Visuals); err != nil {
+		return err
+	}
+
+	if _, err := preset.Animations.createAnimationsInstruction(fixture.Data.Visuals.GetAnimations()); err != nil {
+		return err
+	}
+
+	if _, err := preset.Texts.createTextInstructions(); err != nil {
+		return err
+	}
+
+	//The overrides mark the lightshow as dirty, so auto mode picks with the new configuration right away
+	fixture.Data.Visuals.UpdateConfiguration(func(configuration *Lightshow.AutoModeConfiguration) {
+		*configuration = preset.AutoMode
+	}, false)
+
+	if err := fixture.setLightingOverride(preset.Lighting, fade); err != nil {
+		return err
+	}
+
+	if err := fixture.setAnimationsOverride(preset.Animations); err != nil {
+		return err
+	}
+
+	return fixture.setTextsOverride(preset.Texts)
+}
@@ -0,0 +1,122 @@
+package Api
+
+import (
+	"ControlApp/Lightshow"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Preset is a named snapshot of the auto mode configuration and the overrides.
+type Preset struct {
+	Name       string
+	AutoMode   Lightshow.AutoModeConfiguration
+	Lighting   LightingInstructionTotal
+	Animations ScreenOverrideAnimationProperties
+	Texts      ScreenOverrideTextProperties
+}
+
+type PresetManager struct {
+	presets    map[string]Preset
+	accessLock *sync.Mutex
+}
+
+const presetsConfigPath = "Configuration/presets.json"
+const presetsConfigBackupPath = "Configuration/presets_backup.json"
+
+func LoadPresets() *PresetManager {
+	config, err := Lightshow.LoadConfiguration[map[string]Preset](presetsConfigPath)
+	if err != nil {
+		config, err = Lightshow.LoadConfiguration[map[string]Preset](presetsConfigBackupPath)
+	}
+
+	if err != nil {
+		log.Fatalf("Config file for presets could not be accessed! %s", err)
+	}
+
+	if config == nil {
+		config = make(map[string]Preset)
+	}
+
+	return &PresetManager{
+		presets:    config,
+		accessLock: &sync.Mutex{},
+	}
+}
+
+func (manager *PresetManager) storeConfiguration() {
+	Lightshow.StoreConfiguration(&manager.presets, presetsConfigPath, presetsConfigBackupPath)
+}
+
+// GetByName returns a copy of the preset, which can be changed without affecting the stored one.
+func (manager *PresetManager) GetByName(name string) (bool, Preset) {
+	manager.accessLock.Lock()
+	defer manager.accessLock.Unlock()
+
+	preset, ok := manager.presets[name]
+	if !ok {
+		return false, Preset{}
+	}
+
+	return true, preset.clone()
+}
+
+func (manager *PresetManager) GetAll() []Preset {
+	manager.accessLock.Lock()
+	defer manager.accessLock.Unlock()
+
+	var presets []Preset
+	for _, preset := range manager.presets {
+		presets = append(presets, preset.clone())
+	}
+
+	sort.Slice(presets, func(i, j int) bool {
+		return strings.ToLower(presets[i].Name) < strings.ToLower(presets[j].Name)
+	})
+
+	return presets
+}
+
+// SetPreset stores the preset, replacing the one with the same name.
+func (manager *PresetManager) SetPreset(preset Preset) error {
+	if strings.TrimSpace(preset.Name) == "" {
+		return errors.New("a preset needs a name")
+	}
+
+	manager.accessLock.Lock()
+	defer manager.accessLock.Unlock()
+
+	manager.presets[preset.Name] = preset.clone()
+	manager.storeConfiguration()
+	return nil
+}
+
+func (manager *PresetManager) RemovePreset(name string) {
+	manager.accessLock.Lock()
+	defer manager.accessLock.Unlock()
+
+	delete(manager.presets, name)
+	manager.storeConfiguration()
+}
+
+// clone returns a copy of the preset that shares no maps or slices with it.
+func (preset Preset) clone() Preset {
+	preset.AutoMode = preset.AutoMode.Clone()
+	preset.Lighting = preset.Lighting.clone()
+	preset.Animations.Animations = slices.Clone(preset.Animations.Animations)
+	preset.Texts.Texts = slices.Clone(preset.Texts.Texts)
+	return preset
+}
+
+// clone returns a copy of the instruction that doesn't share the lighting of Boxi 2 with it.
+func (data LightingInstructionTotal) clone() LightingInstructionTotal {
+	if data.Boxi2 != nil {
+		boxi2 := data.Boxi2.clone()
+		data.Boxi2 = &boxi2
+	}
+
+	return data
+}
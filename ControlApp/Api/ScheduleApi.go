@@ -0,0 +1,74 @@
+package Api
+
+import (
+	"ControlApp/Lightshow"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type ScheduleData struct {
+	Enabled  bool          `json:"enabled"`
+	Programs []ShowProgram `json:"programs"`
+}
+
+type ShowProgram struct {
+	Start      string `json:"start"` //Time of day as HH:MM
+	Mood       int    `json:"mood"`
+	AllowNsfw  bool   `json:"nsfw"`
+	Brightness int    `json:"brightness"` //Display brightness in percent
+}
+
+func (fixture Fixture) HandleScheduleApi(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		fixture.handleScheduleGetApi(w)
+	case "PUT":
+		fixture.handleScheduleUpdateApi(w, r)
+	default:
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+	}
+}
+
+func (fixture Fixture) handleScheduleGetApi(w http.ResponseWriter) {
+	schedule := fixture.Data.Visuals.GetSchedule().GetSchedule()
+	result := ScheduleData{Enabled: schedule.Enabled, Programs: make([]ShowProgram, 0, len(schedule.Programs))}
+	for _, program := range schedule.Programs {
+		result.Programs = append(result.Programs, ShowProgram{program.Start, int(program.Mood), program.AllowNsfw, program.Brightness})
+	}
+
+	//Encode data
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (fixture Fixture) handleScheduleUpdateApi(w http.ResponseWriter, r *http.Request) {
+	var data ScheduleData
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid parameters. %s", err), http.StatusBadRequest)
+		return
+	}
+
+	schedule := Lightshow.Schedule{Enabled: data.Enabled}
+	for _, program := range data.Programs {
+		if program.Mood < 0 || program.Mood > 3 {
+			http.Error(w, fmt.Sprintf("Illegal mood value '%d'.", program.Mood), http.StatusBadRequest)
+			return
+		}
+
+		schedule.Programs = append(schedule.Programs, Lightshow.ShowProgram{
+			Start:      program.Start,
+			Mood:       Lightshow.LightingMood(program.Mood),
+			AllowNsfw:  program.AllowNsfw,
+			Brightness: program.Brightness,
+		})
+	}
+
+	if err := fixture.Data.Visuals.GetSchedule().SetSchedule(schedule); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid schedule. %s", err), http.StatusBadRequest)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"ControlApp/Display"
 	"ControlApp/Lightshow"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -65,28 +66,47 @@ func (fixture Fixture) HandleSetScreenOverrideAnimationSetApi(w http.ResponseWri
 		return
 	}
 
+	if err := fixture.setAnimationsOverride(data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// setAnimationsOverride stores the override and applies it.
+func (fixture Fixture) setAnimationsOverride(data ScreenOverrideAnimationProperties) error {
+	instruction, err := data.createAnimationsInstruction(fixture.Data.Visuals.GetAnimations())
+	if err != nil {
+		return err
+	}
+
+	fixture.Data.screensLock.Lock()
+	defer fixture.Data.screensLock.Unlock()
+
+	fixture.Data.OverrideAnimationCurrent = data
+	fixture.Data.Visuals.SetAnimationsOverwrite(instruction)
+	fixture.Data.Visuals.MarkLightshowAsDirty()
+	return nil
+}
+
+// createAnimationsInstruction validates the override, it returns nil if the screens are reset to auto mode.
+func (data ScreenOverrideAnimationProperties) createAnimationsInstruction(animations *Lightshow.AnimationManager) (*Lightshow.AnimationsInstruction, error) {
 	if data.ResetScreens {
-		fixture.Data.Visuals.SetAnimationsOverwrite(nil)
-		fixture.Data.Visuals.MarkLightshowAsDirty()
-		return
+		return nil, nil
 	}
 
 	var aniInstr []Lightshow.AnimationInstruction
 
 	for _, animation := range data.Animations {
 		if animation.ScreenIndex < Display.Boxi1D1 || animation.ScreenIndex > Display.Boxi2D2 {
-			http.Error(w, fmt.Sprintf("Screen ID is out of bound. %s", err), http.StatusBadRequest)
-			return
+			return nil, errors.New("Screen ID is out of bound.")
 		}
 
 		if animation.AnimationId == Display.None {
 			continue
 		}
 
-		exists, animationObj := fixture.Data.Visuals.GetAnimations().GetById(animation.AnimationId)
+		exists, animationObj := animations.GetById(animation.AnimationId)
 		if !exists {
-			http.Error(w, fmt.Sprintf("Animation can't be found. %s", err), http.StatusBadRequest)
-			return
+			return nil, errors.New("Animation can't be found.")
 		}
 
 		id := animationObj.Id
@@ -104,8 +124,7 @@ func (fixture Fixture) HandleSetScreenOverrideAnimationSetApi(w http.ResponseWri
 	}
 
 	instr := Lightshow.AnimationsInstruction{Animations: aniInstr, Character: Lightshow.Unknown, BlinkSpeed: uint16(data.FadeoutSpeed)}
-	fixture.Data.Visuals.SetAnimationsOverwrite(&instr)
-	fixture.Data.Visuals.MarkLightshowAsDirty()
+	return &instr, nil
 }
 
 func (fixture Fixture) HandleSetScreenOverrideTextSetApi(w http.ResponseWriter, r *http.Request) {
@@ -122,12 +141,33 @@ func (fixture Fixture) HandleSetScreenOverrideTextSetApi(w http.ResponseWriter,
 		return
 	}
 
+	if err := fixture.setTextsOverride(data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// setTextsOverride stores the texts and shows them.
+func (fixture Fixture) setTextsOverride(data ScreenOverrideTextProperties) error {
+	instructions, err := data.createTextInstructions()
+	if err != nil {
+		return err
+	}
+
+	fixture.Data.screensLock.Lock()
+	defer fixture.Data.screensLock.Unlock()
+
+	fixture.Data.OverrideTextsCurrent = data
+	fixture.Data.Visuals.SetTexts(instructions)
+	return nil
+}
+
+// createTextInstructions validates the texts.
+func (data ScreenOverrideTextProperties) createTextInstructions() ([]Lightshow.TextInstruction, error) {
 	var textInstr []Lightshow.TextInstruction
 
 	for _, text := range data.Texts {
 		if text.ScreenIndex < Display.Boxi1D1 || text.ScreenIndex > Display.Boxi2D2 {
-			http.Error(w, fmt.Sprintf("Screen ID is out of bound. %s", err), http.StatusBadRequest)
-			return
+			return nil, errors.New("Screen ID is out of bound.")
 		}
 		textContent := text.Text
 		if strings.TrimSpace(textContent) == "" {
@@ -137,7 +177,7 @@ func (fixture Fixture) HandleSetScreenOverrideTextSetApi(w http.ResponseWriter,
 		textInstr = append(textInstr, Lightshow.TextInstruction{Text: text.Text, Displays: []Display.ServerDisplay{text.ScreenIndex}})
 	}
 
-	fixture.Data.Visuals.SetTexts(textInstr)
+	return textInstr, nil
 }
 
 func (fixture Fixture) HandleSetScreenOverrideBrightnessLevelApi(w http.ResponseWriter, r *http.Request) {
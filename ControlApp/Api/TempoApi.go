@@ -0,0 +1,30 @@
+package Api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type TempoState struct {
+	Bpm        float64 `json:"bpm"`        //0 if the tempo is unknown
+	Confidence float64 `json:"confidence"` //From 0 to 1
+}
+
+func (fixture Fixture) HandleTempoApi(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tempo := fixture.Data.Visuals.GetTempo()
+	result := TempoState{
+		Bpm:        tempo.CurrentBPM(),
+		Confidence: tempo.Confidence(),
+	}
+
+	//Encode data
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
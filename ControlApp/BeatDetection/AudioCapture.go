@@ -0,0 +1,104 @@
+package BeatDetection
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultCaptureDevice = "default"
+	defaultSampleRate    = 44100
+)
+
+// audioCapture detects beats in the audio captured by arecord, which streams mono 16 bit PCM.
+// arecord is part of alsa-utils and has to be installed on the host.
+type audioCapture struct {
+	beatQueue
+	command *exec.Cmd
+	exited  chan struct{} //Closed once arecord exited and was reaped
+}
+
+func openAudioCapture(config SourceConfiguration) (*audioCapture, error) {
+	device := config.Device
+	if device == "" {
+		device = defaultCaptureDevice
+	}
+
+	sampleRate := config.SampleRate
+	if sampleRate == 0 {
+		sampleRate = defaultSampleRate
+	}
+
+	if _, err := exec.LookPath("arecord"); err != nil {
+		return nil, fmt.Errorf("audio capture needs arecord, install alsa-utils: %w", err)
+	}
+
+	command := exec.Command("arecord", "-q", "-D", device, "-f", "S16_LE", "-c", "1", "-r", strconv.Itoa(sampleRate), "-t", "raw")
+	output, err := command.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := command.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start audio capture on %s: %w", device, err)
+	}
+
+	capture := &audioCapture{beatQueue: make(beatQueue, beatQueueSize), command: command, exited: make(chan struct{})}
+	go capture.detect(output, CreateOnsetDetector(sampleRate, config.Sensitivity))
+	return capture, nil
+}
+
+func (capture *audioCapture) detect(output io.Reader, detector *OnsetDetector) {
+	defer close(capture.exited)
+
+	samples := make([]float64, hopSize)
+	for {
+		if err := readSamples(output, 1, samples); err != nil {
+			log.Printf("Audio capture stopped, no more beats are detected: %s", err)
+
+			//Reap arecord, all output was read
+			_ = capture.command.Wait()
+			return
+		}
+
+		if detector.Process(samples) {
+			capture.report(time.Now())
+		}
+	}
+}
+
+// Close stops arecord and waits until it exited.
+func (capture *audioCapture) Close() error {
+	err := capture.command.Process.Kill()
+	<-capture.exited
+	if errors.Is(err, os.ErrProcessDone) {
+		return nil
+	}
+
+	return err
+}
+
+// readSamples fills the samples with 16 bit little endian PCM, mixing all channels down to mono.
+func readSamples(reader io.Reader, channels int, samples []float64) error {
+	frames := make([]int16, len(samples)*channels)
+	if err := binary.Read(reader, binary.LittleEndian, frames); err != nil {
+		return err
+	}
+
+	for i := range samples {
+		sum := 0.0
+		for _, value := range frames[i*channels : (i+1)*channels] {
+			sum += float64(value)
+		}
+		samples[i] = sum / float64(channels) / 32768
+	}
+
+	return nil
+}
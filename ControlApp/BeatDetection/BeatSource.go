@@ -0,0 +1,62 @@
+package BeatDetection
+
+import (
+	"fmt"
+	"time"
+)
+
+// BeatSource reports the beats of the music playing at the Boxis.
+type BeatSource interface {
+	Beats() <-chan time.Time //Delivers the time of every beat
+	Close() error
+}
+
+// beatQueue buffers the beats of a source until they are taken. Beats that don't fit are dropped,
+// as they are stale by the time they would be taken.
+type beatQueue chan time.Time
+
+const beatQueueSize = 4
+
+func (queue beatQueue) report(at time.Time) {
+	select {
+	case queue <- at:
+	default:
+	}
+}
+
+func (queue beatQueue) Beats() <-chan time.Time {
+	return queue
+}
+
+// SourceType selects where the beats come from.
+type SourceType string
+
+const (
+	ImpulseInput SourceType = "gpio" //Beat impulses of the external hardware at the impulse input pin
+	AudioCapture SourceType = "alsa" //Onsets detected in the audio captured from an ALSA device, requires arecord
+	WavFile      SourceType = "wav"  //Onsets detected in a WAV file played in real time, for testing
+)
+
+// SourceConfiguration describes the beat source.
+type SourceConfiguration struct {
+	Type        SourceType
+	Device      string  //The ALSA capture device, "default" if not set
+	SampleRate  int     //The capture sample rate, 44100 Hz if not set
+	File        string  //The WAV file to detect beats in
+	Loop        bool    //Restart the WAV file when it ended
+	Sensitivity float64 //Standard deviations the spectral flux must exceed its average by, 1.5 if not set
+}
+
+// CreateBeatSource opens the configured beat source.
+func CreateBeatSource(config SourceConfiguration) (BeatSource, error) {
+	switch config.Type {
+	case ImpulseInput, "":
+		return openImpulseInput()
+	case AudioCapture:
+		return openAudioCapture(config)
+	case WavFile:
+		return openWavFile(config)
+	default:
+		return nil, fmt.Errorf("unknown beat source %q", config.Type)
+	}
+}
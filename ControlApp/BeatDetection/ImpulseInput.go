@@ -0,0 +1,52 @@
+package BeatDetection
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/host/v3"
+	"periph.io/x/host/v3/rpi"
+)
+
+// impulseInput reports the rising edges of the beat impulses of the external hardware.
+type impulseInput struct {
+	beatQueue
+	pin    gpio.PinIO
+	closed atomic.Bool
+}
+
+func openImpulseInput() (*impulseInput, error) {
+	// Initialize periph.io
+	if _, err := host.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize periph: %s", err)
+	}
+
+	// Use rpi.P1_16 (GPIO23 on physical pin 16)
+	pin := rpi.P1_16
+
+	// Set as input that detects the start of the impulses
+	if err := pin.In(gpio.PullDown, gpio.RisingEdge); err != nil {
+		return nil, fmt.Errorf("failed to set pin as input: %s", err)
+	}
+
+	input := &impulseInput{beatQueue: make(beatQueue, beatQueueSize), pin: pin}
+	go input.waitForImpulses()
+	return input, nil
+}
+
+func (input *impulseInput) waitForImpulses() {
+	for !input.closed.Load() {
+		if input.pin.WaitForEdge(-1) {
+			input.report(time.Now())
+		}
+	}
+}
+
+func (input *impulseInput) Close() error {
+	input.closed.Store(true)
+
+	//Stops the edge detection, which wakes up the waiting goroutine
+	return input.pin.Halt()
+}
@@ -0,0 +1,152 @@
+package BeatDetection
+
+import (
+	"math"
+	"math/cmplx"
+	"time"
+)
+
+const (
+	frameSize          = 1024                   //Samples per analysed frame, must be a power of two
+	hopSize            = frameSize / 2          //Samples between the starts of two frames
+	fluxHistory        = time.Second            //The span the flux average is taken over
+	minOnsetDistance   = 100 * time.Millisecond //Onsets closer than this are counted once
+	defaultSensitivity = 1.5
+	silenceFlux        = 1.0 //Flux below this is considered silence, even if it exceeds the average
+)
+
+// OnsetDetector finds note onsets in mono audio by their spectral flux, the rise of the spectrum
+// from one frame to the next. A frame is an onset if its flux stands out from the recent average.
+type OnsetDetector struct {
+	sensitivity  float64
+	window       []float64
+	frame        []float64 //Ring buffer of the last frameSize samples
+	frameIndex   int       //Position of the oldest sample in the frame
+	filled       int       //Samples in the frame since the last analysis
+	spectrum     []float64 //Log magnitude spectrum of the last frame
+	history      []float64 //Ring buffer of the recent flux values
+	historyIndex int
+	holdOff      int //Frames left until the next onset may be reported
+	holdOffSize  int
+}
+
+// CreateOnsetDetector returns a detector for audio of the given sample rate. A sensitivity of 0 uses the default.
+func CreateOnsetDetector(sampleRate int, sensitivity float64) *OnsetDetector {
+	if sensitivity <= 0 {
+		sensitivity = defaultSensitivity
+	}
+
+	hopDuration := float64(hopSize) / float64(sampleRate)
+	detector := &OnsetDetector{
+		sensitivity: sensitivity,
+		window:      make([]float64, frameSize),
+		frame:       make([]float64, frameSize),
+		spectrum:    make([]float64, frameSize/2),
+		history:     make([]float64, max(int(fluxHistory.Seconds()/hopDuration), 1)),
+		holdOffSize: int(math.Ceil(minOnsetDistance.Seconds() / hopDuration)),
+	}
+
+	//Hann window
+	for i := range detector.window {
+		detector.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frameSize-1))
+	}
+
+	return detector
+}
+
+// Process feeds samples from -1 to 1 into the detector and returns whether an onset was found in them.
+func (detector *OnsetDetector) Process(samples []float64) bool {
+	onset := false
+	for _, sample := range samples {
+		detector.frame[detector.frameIndex] = sample
+		detector.frameIndex = (detector.frameIndex + 1) % frameSize
+
+		if detector.filled++; detector.filled == hopSize {
+			detector.filled = 0
+			onset = detector.analyse() || onset
+		}
+	}
+
+	return onset
+}
+
+// analyse calculates the flux of the current frame and decides whether it is an onset.
+func (detector *OnsetDetector) analyse() bool {
+	buffer := make([]complex128, frameSize)
+	for i := range buffer {
+		sample := detector.frame[(detector.frameIndex+i)%frameSize]
+		buffer[i] = complex(sample*detector.window[i], 0)
+	}
+	fft(buffer)
+
+	flux := 0.0
+	for i := range detector.spectrum {
+		//Log compression keeps loud passages from drowning out the quiet ones
+		magnitude := math.Log1p(100 * cmplx.Abs(buffer[i]))
+		flux += max(magnitude-detector.spectrum[i], 0)
+		detector.spectrum[i] = magnitude
+	}
+
+	mean, deviation := detector.getFluxStatistics()
+	detector.history[detector.historyIndex] = flux
+	detector.historyIndex = (detector.historyIndex + 1) % len(detector.history)
+
+	if detector.holdOff > 0 {
+		detector.holdOff--
+		return false
+	}
+
+	if flux < silenceFlux || flux <= mean+detector.sensitivity*deviation {
+		return false
+	}
+
+	detector.holdOff = detector.holdOffSize
+	return true
+}
+
+func (detector *OnsetDetector) getFluxStatistics() (float64, float64) {
+	mean := 0.0
+	for _, value := range detector.history {
+		mean += value
+	}
+	mean /= float64(len(detector.history))
+
+	variance := 0.0
+	for _, value := range detector.history {
+		variance += (value - mean) * (value - mean)
+	}
+
+	return mean, math.Sqrt(variance / float64(len(detector.history)))
+}
+
+// fft transforms the buffer in place. Its length must be a power of two.
+func fft(buffer []complex128) {
+	count := len(buffer)
+
+	//Bit reversal permutation
+	for i, j := 1, 0; i < count; i++ {
+		bit := count >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+
+		if i < j {
+			buffer[i], buffer[j] = buffer[j], buffer[i]
+		}
+	}
+
+	for size := 2; size <= count; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < count; start += size {
+			twiddle := complex(1, 0)
+			for i := 0; i < size/2; i++ {
+				even := buffer[start+i]
+				odd := buffer[start+i+size/2] * twiddle
+				buffer[start+i] = even + odd
+				buffer[start+i+size/2] = even - odd
+				twiddle *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package BeatDetection
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const testSampleRate = 44100
+
+// createClickTrack returns mono audio with a short decaying noise burst at each of the click times.
+func createClickTrack(duration time.Duration, clicks []time.Duration) []float64 {
+	random := rand.New(rand.NewSource(1))
+	samples := make([]float64, int(duration.Seconds()*testSampleRate))
+
+	for _, click := range clicks {
+		start := int(click.Seconds() * testSampleRate)
+		for i := 0; i < testSampleRate/100 && start+i < len(samples); i++ {
+			samples[start+i] = 0.8 * (2*random.Float64() - 1) * math.Exp(-float64(i)/100)
+		}
+	}
+
+	return samples
+}
+
+// detectOnsets feeds the samples to a new detector hop by hop and returns when onsets were reported.
+func detectOnsets(samples []float64) []time.Duration {
+	detector := CreateOnsetDetector(testSampleRate, 0)
+
+	var onsets []time.Duration
+	for start := 0; start+hopSize <= len(samples); start += hopSize {
+		if detector.Process(samples[start : start+hopSize]) {
+			end := time.Duration(start+hopSize) * time.Second / testSampleRate
+			onsets = append(onsets, end)
+		}
+	}
+
+	return onsets
+}
+
+// expectOnsets checks that an onset was reported for each click, no later than one frame after it.
+func expectOnsets(t *testing.T, onsets []time.Duration, clicks []time.Duration) {
+	t.Helper()
+
+	maxDelay := time.Duration(frameSize) * time.Second / testSampleRate
+	if len(onsets) != len(clicks) {
+		t.Fatalf("detected onsets at %v, expected %v", onsets, clicks)
+	}
+	for i, click := range clicks {
+		if delay := onsets[i] - click; delay < 0 || delay > maxDelay {
+			t.Errorf("detected the click at %v at %v", click, onsets[i])
+		}
+	}
+}
+
+func TestFft(t *testing.T) {
+	const size = 16
+
+	tests := []struct {
+		name     string
+		signal   func(i int) complex128
+		expected func(k int) complex128
+	}{
+		{
+			name:     "impulse",
+			signal:   func(i int) complex128 { return complex(float64(1-min(i, 1)), 0) },
+			expected: func(k int) complex128 { return 1 },
+		},
+		{
+			name:     "constant",
+			signal:   func(i int) complex128 { return 1 },
+			expected: func(k int) complex128 { return complex(float64(size*(1-min(k, 1))), 0) },
+		},
+		{
+			name:   "cosine",
+			signal: func(i int) complex128 { return complex(math.Cos(2*math.Pi*3*float64(i)/size), 0) },
+			expected: func(k int) complex128 {
+				if k == 3 || k == size-3 {
+					return size / 2
+				}
+				return 0
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := make([]complex128, size)
+			for i := range buffer {
+				buffer[i] = test.signal(i)
+			}
+			fft(buffer)
+
+			for k, value := range buffer {
+				if cmplx.Abs(value-test.expected(k)) > 1e-9 {
+					t.Errorf("bin %d is %v, expected %v", k, value, test.expected(k))
+				}
+			}
+		})
+	}
+}
+
+func TestOnsetDetector(t *testing.T) {
+	tests := []struct {
+		name   string
+		clicks []time.Duration
+	}{
+		{
+			name: "silence",
+		},
+		{
+			name:   "click train at 120 BPM",
+			clicks: []time.Duration{250 * time.Millisecond, 750 * time.Millisecond, 1250 * time.Millisecond, 1750 * time.Millisecond, 2250 * time.Millisecond},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			onsets := detectOnsets(createClickTrack(3*time.Second, test.clicks))
+			expectOnsets(t, onsets, test.clicks)
+		})
+	}
+}
+
+func TestOnsetDetectorHoldsOff(t *testing.T) {
+	//The second click is closer than minOnsetDistance, so it's counted as part of the first
+	clicks := []time.Duration{500 * time.Millisecond, 550 * time.Millisecond}
+
+	onsets := detectOnsets(createClickTrack(time.Second, clicks))
+	expectOnsets(t, onsets, clicks[:1])
+}
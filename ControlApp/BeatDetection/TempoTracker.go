@@ -0,0 +1,181 @@
+package BeatDetection
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	minBpm                = 60
+	maxBpm                = 200
+	periodStep            = 5 * time.Millisecond //Resolution of the period search
+	tempoWindow           = 8 * time.Second      //Only beats this recent are used to estimate the tempo
+	minTrackedBeats       = 4                    //Beats needed before a tempo is estimated
+	periodTolerance       = 0.1                  //Deviation of an interval from a multiple of the period, relative to the period
+	doubleTriggerFraction = 0.5                  //Beats closer than this fraction of the period to the last one are double triggers
+	fillDelayFraction     = 0.15                 //How long a late beat is waited for before it is filled in, relative to the period
+	maxFilledBeats        = 4                    //Missed beats filled in a row, the music probably stopped after that
+	lockedConfidence      = 0.5                  //Confidence from which the tempo is trusted
+)
+
+// TempoTracker estimates the tempo and phase of the music from the detected beats. Once it is confident
+// about the tempo, it rejects double triggers and fills in missed beats.
+type TempoTracker struct {
+	lock        sync.Mutex
+	beats       []time.Time //The recent observed beats
+	period      time.Duration
+	confidence  float64
+	lastBeat    time.Time //The last beat, observed or filled in
+	filledBeats int       //Beats filled in since the last observed one
+}
+
+func CreateTempoTracker() *TempoTracker {
+	return &TempoTracker{}
+}
+
+// AddBeat reports a detected beat and returns whether it counts as a beat, it doesn't if it is a double trigger.
+func (tracker *TempoTracker) AddBeat(at time.Time) bool {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if tracker.isLocked() && at.Sub(tracker.lastBeat) < time.Duration(float64(tracker.period)*doubleTriggerFraction) {
+		//A beat shortly after a filled in one is the late original, which corrects the phase
+		if tracker.filledBeats > 0 {
+			tracker.lastBeat = at
+		}
+		return false
+	}
+
+	tracker.beats = append(tracker.beats, at)
+	for len(tracker.beats) > 0 && at.Sub(tracker.beats[0]) > tempoWindow {
+		tracker.beats = tracker.beats[1:]
+	}
+
+	tracker.lastBeat = at
+	tracker.filledBeats = 0
+	tracker.estimate()
+	return true
+}
+
+// Update returns whether a beat was missed at the given time and is filled in.
+func (tracker *TempoTracker) Update(now time.Time) bool {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	deadline, ok := tracker.getFillDeadline()
+	if !ok || now.Before(deadline) {
+		return false
+	}
+
+	//Stay on the beat grid instead of drifting by the fill delay
+	tracker.lastBeat = tracker.lastBeat.Add(tracker.period)
+	tracker.filledBeats++
+	return true
+}
+
+// FillDeadline returns when the next beat is filled in if it isn't detected. The second value is false
+// if no beat will be filled in.
+func (tracker *TempoTracker) FillDeadline() (time.Time, bool) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	return tracker.getFillDeadline()
+}
+
+// getFillDeadline requires the lock.
+func (tracker *TempoTracker) getFillDeadline() (time.Time, bool) {
+	if !tracker.isLocked() || tracker.filledBeats >= maxFilledBeats {
+		return time.Time{}, false
+	}
+
+	fillDelay := time.Duration(float64(tracker.period) * fillDelayFraction)
+	return tracker.lastBeat.Add(tracker.period + fillDelay), true
+}
+
+// CurrentBPM returns the estimated tempo, 0 if it is unknown or the music stopped.
+func (tracker *TempoTracker) CurrentBPM() float64 {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if tracker.period == 0 || tracker.filledBeats >= maxFilledBeats {
+		return 0
+	}
+
+	return time.Minute.Seconds() / tracker.period.Seconds()
+}
+
+// Confidence returns how well the recent beats fit the estimated tempo, from 0 to 1.
+func (tracker *TempoTracker) Confidence() float64 {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	return tracker.confidence
+}
+
+// isLocked returns whether the tempo is trusted. Requires the lock.
+func (tracker *TempoTracker) isLocked() bool {
+	return tracker.period > 0 && tracker.confidence >= lockedConfidence
+}
+
+// estimate finds the period most intervals between the recent beats are a multiple of. Requires the lock.
+// Matches of multiples count less, so a tempo isn't mistaken for half of it.
+func (tracker *TempoTracker) estimate() {
+	if len(tracker.beats) < minTrackedBeats {
+		tracker.period = 0
+		tracker.confidence = 0
+		return
+	}
+
+	intervals := make([]time.Duration, len(tracker.beats)-1)
+	for i := range intervals {
+		intervals[i] = tracker.beats[i+1].Sub(tracker.beats[i])
+	}
+
+	minPeriod := time.Minute / maxBpm
+	maxPeriod := time.Minute / minBpm
+
+	bestScore := 0.0
+	var bestPeriod time.Duration
+	for period := minPeriod; period <= maxPeriod; period += periodStep {
+		score := 0.0
+		for _, interval := range intervals {
+			multiple, deviation := getPeriodDeviation(interval, period)
+			if multiple > 0 && deviation < periodTolerance {
+				score += (1 - deviation/periodTolerance) / float64(multiple)
+			}
+		}
+
+		if score > bestScore {
+			bestScore = score
+			bestPeriod = period
+		}
+	}
+
+	if bestPeriod == 0 {
+		tracker.period = 0
+		tracker.confidence = 0
+		return
+	}
+
+	//Refine the period with the intervals that match it
+	sum := 0.0
+	count := 0
+	for _, interval := range intervals {
+		multiple, deviation := getPeriodDeviation(interval, bestPeriod)
+		if multiple > 0 && deviation < periodTolerance {
+			sum += interval.Seconds() / float64(multiple)
+			count++
+		}
+	}
+
+	tracker.period = time.Duration(sum / float64(count) * float64(time.Second))
+	tracker.confidence = bestScore / float64(len(intervals))
+}
+
+// getPeriodDeviation returns the multiple of the period closest to the interval and how far off it is, relative to the period.
+func getPeriodDeviation(interval time.Duration, period time.Duration) (int, float64) {
+	ratio := interval.Seconds() / period.Seconds()
+	multiple := math.Round(ratio)
+	return int(multiple), math.Abs(ratio - multiple)
+}
@@ -0,0 +1,142 @@
+package BeatDetection
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+const trackerTick = 10 * time.Millisecond
+
+// createBeats returns the times of a click train with the given interval, leaving out the missing beats.
+func createBeats(count int, interval time.Duration, missing ...int) []time.Duration {
+	var beats []time.Duration
+	for i := 0; i < count; i++ {
+		if !slices.Contains(missing, i) {
+			beats = append(beats, time.Duration(i)*interval)
+		}
+	}
+
+	return beats
+}
+
+func TestTempoTracker(t *testing.T) {
+	tests := []struct {
+		name          string
+		beats         []time.Duration //Must be multiples of trackerTick
+		end           time.Duration
+		counted       int
+		filled        int
+		bpm           float64
+		minConfidence float64
+	}{
+		{
+			name:    "too few beats",
+			beats:   createBeats(3, 500*time.Millisecond),
+			end:     5 * time.Second,
+			counted: 3,
+		},
+		{
+			name:          "steady click train",
+			beats:         createBeats(8, 500*time.Millisecond),
+			end:           3500 * time.Millisecond,
+			counted:       8,
+			bpm:           120,
+			minConfidence: 0.99,
+		},
+		{
+			name:          "double triggers",
+			beats:         append(createBeats(8, 500*time.Millisecond), 2030*time.Millisecond, 2520*time.Millisecond, 3100*time.Millisecond),
+			end:           3500 * time.Millisecond,
+			counted:       8,
+			bpm:           120,
+			minConfidence: 0.99,
+		},
+		{
+			name:          "dropout",
+			beats:         createBeats(8, 500*time.Millisecond, 5),
+			end:           3500 * time.Millisecond,
+			counted:       7,
+			filled:        1,
+			bpm:           120,
+			minConfidence: 0.9,
+		},
+		{
+			//The late beat is taken as the filled in one instead of counting twice
+			name:          "late beat after a dropout",
+			beats:         append(createBeats(8, 500*time.Millisecond, 5), 2600*time.Millisecond),
+			end:           3500 * time.Millisecond,
+			counted:       7,
+			filled:        1,
+			bpm:           120,
+			minConfidence: 0.9,
+		},
+		{
+			name:          "music stopped",
+			beats:         createBeats(8, 500*time.Millisecond),
+			end:           10 * time.Second,
+			counted:       8,
+			filled:        maxFilledBeats,
+			minConfidence: 0.99,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+			tracker := CreateTempoTracker()
+
+			counted := 0
+			filled := 0
+			for now := time.Duration(0); now <= test.end; now += trackerTick {
+				for _, beat := range test.beats {
+					if beat == now && tracker.AddBeat(start.Add(now)) {
+						counted++
+					}
+				}
+
+				if tracker.Update(start.Add(now)) {
+					filled++
+				}
+			}
+
+			if counted != test.counted || filled != test.filled {
+				t.Errorf("counted %d and filled in %d beats, expected %d and %d", counted, filled, test.counted, test.filled)
+			}
+			if bpm := tracker.CurrentBPM(); math.Abs(bpm-test.bpm) > 0.5 {
+				t.Errorf("estimated %.1f BPM, expected %.1f", bpm, test.bpm)
+			}
+			if confidence := tracker.Confidence(); confidence < test.minConfidence {
+				t.Errorf("confidence is %.2f, expected at least %.2f", confidence, test.minConfidence)
+			}
+		})
+	}
+}
+
+func TestTempoTrackerFillsOnTheBeatGrid(t *testing.T) {
+	start := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+	tracker := CreateTempoTracker()
+	for _, beat := range createBeats(8, 500*time.Millisecond) {
+		tracker.AddBeat(start.Add(beat))
+	}
+
+	deadline, ok := tracker.FillDeadline()
+	expected := start.Add(4*time.Second + time.Duration(fillDelayFraction*float64(500*time.Millisecond)))
+	if !ok || !deadline.Equal(expected) {
+		t.Fatalf("fill deadline is %v (%t), expected %v", deadline, ok, expected)
+	}
+
+	if tracker.Update(deadline.Add(-time.Millisecond)) {
+		t.Error("filled in a beat before the deadline")
+	}
+	if !tracker.Update(deadline) {
+		t.Fatal("didn't fill in a beat at the deadline")
+	}
+
+	//The next beat is expected a period after the filled in one, not after the deadline
+	next, _ := tracker.FillDeadline()
+	if expected = expected.Add(500 * time.Millisecond); !next.Equal(expected) {
+		t.Errorf("next fill deadline is %v, expected %v", next, expected)
+	}
+}
@@ -0,0 +1,154 @@
+package BeatDetection
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	wavPcmFormat          = 1
+	maxWavFormatChunkSize = 40 //The extensible format chunk is the longest one
+)
+
+// wavFile detects beats in a WAV file, which is played in real time so beats arrive like they would live.
+type wavFile struct {
+	beatQueue
+	stop chan struct{}
+}
+
+type wavFormat struct {
+	channels   int
+	sampleRate int
+}
+
+func openWavFile(config SourceConfiguration) (*wavFile, error) {
+	//Check the file once, so configuration mistakes show up right away
+	file, _, _, err := openWavData(config.File)
+	if err != nil {
+		return nil, err
+	}
+	_ = file.Close()
+
+	source := &wavFile{beatQueue: make(beatQueue, beatQueueSize), stop: make(chan struct{})}
+	go source.play(config)
+	return source, nil
+}
+
+func (source *wavFile) play(config SourceConfiguration) {
+	for {
+		file, data, format, err := openWavData(config.File)
+		if err != nil {
+			log.Printf("WAV beat source stopped: %s", err)
+			return
+		}
+
+		err = source.detect(data, format, CreateOnsetDetector(format.sampleRate, config.Sensitivity))
+		_ = file.Close()
+
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			log.Printf("WAV beat source stopped: %s", err)
+			return
+		}
+
+		if err == nil || !config.Loop {
+			return
+		}
+	}
+}
+
+// detect feeds the audio to the detector at the speed it would be played at. Returns nil when stopped.
+func (source *wavFile) detect(data io.Reader, format wavFormat, detector *OnsetDetector) error {
+	ticker := time.NewTicker(time.Second * hopSize / time.Duration(format.sampleRate))
+	defer ticker.Stop()
+
+	samples := make([]float64, hopSize)
+	for {
+		select {
+		case <-source.stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := readSamples(data, format.channels, samples); err != nil {
+			return err
+		}
+
+		if detector.Process(samples) {
+			source.report(time.Now())
+		}
+	}
+}
+
+func (source *wavFile) Close() error {
+	close(source.stop)
+	return nil
+}
+
+// openWavData opens a 16 bit PCM WAV file and returns a reader positioned at the audio data.
+func openWavData(path string) (*os.File, io.Reader, wavFormat, error) {
+	var format wavFormat
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, format, err
+	}
+
+	reader := bufio.NewReader(file)
+	var header [12]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		_ = file.Close()
+		return nil, nil, format, fmt.Errorf("%s is not a WAV file", path)
+	}
+
+	for {
+		var chunkHeader [8]byte
+		if _, err := io.ReadFull(reader, chunkHeader[:]); err != nil {
+			_ = file.Close()
+			return nil, nil, format, fmt.Errorf("%s has no audio data", path)
+		}
+
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+		switch string(chunkHeader[0:4]) {
+		case "fmt ":
+			//The size is checked first, so a broken file can't make us allocate up to 4 GiB
+			if size < 16 || size > maxWavFormatChunkSize {
+				_ = file.Close()
+				return nil, nil, format, fmt.Errorf("%s has an invalid format chunk", path)
+			}
+
+			chunk := make([]byte, size+size%2)
+			if _, err := io.ReadFull(reader, chunk); err != nil {
+				_ = file.Close()
+				return nil, nil, format, fmt.Errorf("%s has an invalid format chunk", path)
+			}
+
+			bitsPerSample := binary.LittleEndian.Uint16(chunk[14:16])
+			if binary.LittleEndian.Uint16(chunk[0:2]) != wavPcmFormat || bitsPerSample != 16 {
+				_ = file.Close()
+				return nil, nil, format, fmt.Errorf("%s is not 16 bit PCM", path)
+			}
+
+			format.channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			format.sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+		case "data":
+			if format.channels == 0 || format.sampleRate == 0 {
+				_ = file.Close()
+				return nil, nil, format, fmt.Errorf("%s has no format before its audio data", path)
+			}
+
+			return file, io.LimitReader(reader, size), format, nil
+		default:
+			//Chunks are padded to an even size
+			if _, err := reader.Discard(int(size + size%2)); err != nil {
+				_ = file.Close()
+				return nil, nil, format, fmt.Errorf("%s has no audio data", path)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package BeatDetection
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// wavChunk is a RIFF chunk, written with its padding byte if its size is odd.
+type wavChunk struct {
+	id   string
+	data []byte
+}
+
+func createFormatChunk(format uint16, channels uint16, bitsPerSample uint16) wavChunk {
+	data := make([]byte, 16)
+	binary.LittleEndian.PutUint16(data[0:2], format)
+	binary.LittleEndian.PutUint16(data[2:4], channels)
+	binary.LittleEndian.PutUint32(data[4:8], testSampleRate)
+	binary.LittleEndian.PutUint32(data[8:12], testSampleRate*uint32(channels)*uint32(bitsPerSample)/8)
+	binary.LittleEndian.PutUint16(data[12:14], channels*bitsPerSample/8)
+	binary.LittleEndian.PutUint16(data[14:16], bitsPerSample)
+	return wavChunk{"fmt ", data}
+}
+
+// createStereoData encodes the samples as 16 bit PCM with the second channel inverted and half as loud.
+func createStereoData(samples []float64) wavChunk {
+	var data bytes.Buffer
+	for _, sample := range samples {
+		left := int16(sample * 32767)
+		_ = binary.Write(&data, binary.LittleEndian, []int16{left, -left / 2})
+	}
+
+	return wavChunk{"data", data.Bytes()}
+}
+
+func writeWavFile(t *testing.T, chunks ...wavChunk) string {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	for _, chunk := range chunks {
+		body.WriteString(chunk.id)
+		_ = binary.Write(&body, binary.LittleEndian, uint32(len(chunk.data)))
+		body.Write(chunk.data)
+		if len(chunk.data)%2 != 0 {
+			body.WriteByte(0)
+		}
+	}
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	_ = binary.Write(&file, binary.LittleEndian, uint32(body.Len()))
+	file.Write(body.Bytes())
+
+	path := filepath.Join(t.TempDir(), "test.wav")
+	if err := os.WriteFile(path, file.Bytes(), 0o644); err != nil {
+		t.Fatalf("writing the WAV file failed: %s", err)
+	}
+
+	return path
+}
+
+func TestOpenWavData(t *testing.T) {
+	clicks := []time.Duration{250 * time.Millisecond, 750 * time.Millisecond, 1250 * time.Millisecond}
+	samples := createClickTrack(1500*time.Millisecond, clicks)
+
+	//The odd sized chunk before the data checks that the padding is skipped
+	path := writeWavFile(t, createFormatChunk(wavPcmFormat, 2, 16), wavChunk{"LIST", []byte("odd")}, createStereoData(samples))
+
+	file, data, format, err := openWavData(path)
+	if err != nil {
+		t.Fatalf("opening failed: %s", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if format != (wavFormat{channels: 2, sampleRate: testSampleRate}) {
+		t.Fatalf("read format %+v", format)
+	}
+
+	var mixed []float64
+	buffer := make([]float64, hopSize)
+	for readSamples(data, format.channels, buffer) == nil {
+		mixed = append(mixed, buffer...)
+	}
+	if len(mixed) != len(samples)/hopSize*hopSize {
+		t.Fatalf("read %d samples, expected %d", len(mixed), len(samples)/hopSize*hopSize)
+	}
+
+	expectOnsets(t, detectOnsets(mixed), clicks)
+}
+
+func TestOpenWavDataRejectsInvalidFiles(t *testing.T) {
+	audio := createStereoData(make([]float64, hopSize))
+
+	tests := []struct {
+		name   string
+		chunks []wavChunk
+	}{
+		{"no format", []wavChunk{audio}},
+		{"no audio data", []wavChunk{createFormatChunk(wavPcmFormat, 2, 16)}},
+		{"8 bit", []wavChunk{createFormatChunk(wavPcmFormat, 2, 8), audio}},
+		{"compressed", []wavChunk{createFormatChunk(3, 2, 16), audio}},
+		{"short format chunk", []wavChunk{{"fmt ", make([]byte, 14)}, audio}},
+		{"huge format chunk", []wavChunk{{"fmt ", make([]byte, 4096)}, audio}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, _, _, err := openWavData(writeWavFile(t, test.chunks...))
+			if err == nil {
+				_ = file.Close()
+				t.Fatal("opened an invalid file")
+			}
+		})
+	}
+}
+
+func TestOpenWavDataRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte("not a WAV file at all"), 0o644); err != nil {
+		t.Fatalf("writing the file failed: %s", err)
+	}
+
+	if file, _, _, err := openWavData(path); err == nil {
+		_ = file.Close()
+		t.Fatal("opened a text file")
+	}
+}
@@ -0,0 +1,238 @@
+package BoxiBus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LightingFieldSet mirrors one of the two lighting field sets on the Arduino.
+type LightingFieldSet struct {
+	Palette        [MaxPaletteSize]Color
+	PaletteSize    byte
+	Mode           LightingModeId
+	ColorShift     byte
+	Speed          uint16
+	GeneralPurpose byte
+}
+
+// UsedColors returns the colors of the palette that are used by the mode of the field set.
+func (fieldSet LightingFieldSet) UsedColors() []Color {
+	switch fieldSet.Mode {
+	case Off, RainbowCycle:
+		return nil
+	case SetColor, FadeToColor:
+		return fieldSet.Palette[:2]
+	case Strobe:
+		return fieldSet.Palette[:1]
+	}
+
+	return fieldSet.Palette[:min(fieldSet.PaletteSize, MaxPaletteSize)]
+}
+
+// String describes the lighting instruction stored in the field set.
+func (fieldSet LightingFieldSet) String() string {
+	var colors []string
+	for _, color := range fieldSet.UsedColors() {
+		colors = append(colors, color.String())
+	}
+
+	return fmt.Sprintf("mode: %s, palette: [%s], speed: %d, shift: %d, general purpose: %d",
+		fieldSet.Mode, strings.Join(colors, ", "), fieldSet.Speed, fieldSet.ColorShift, fieldSet.GeneralPurpose)
+}
+
+// ArduinoMemory models the memory fields of the Arduino as they are written over the bus.
+// Just like the firmware, every Boxi has a staging field set that lighting fields are written into,
+// which gets swapped with the active one when the lighting is applied.
+type ArduinoMemory struct {
+	fieldSets       [2][2]LightingFieldSet
+	activeField     [2]int
+	transaction     MessageBlock
+	transactionId   *byte
+	paletteOffset   byte
+	target          LightingTarget
+	ApplyOnNextBeat LightingTarget //The Boxis whose lighting gets applied on the next beat
+	StatusCode      DisplayStatusCode
+	StatusServerId  byte
+	InternalLeds    bool
+}
+
+// CreateArduinoMemory returns the memory in the state the firmware boots into.
+func CreateArduinoMemory() *ArduinoMemory {
+	memory := &ArduinoMemory{InternalLeds: true, target: TargetBothBoxis}
+	for boxi := range memory.fieldSets {
+		memory.fieldSets[boxi][0] = LightingFieldSet{
+			Palette: [MaxPaletteSize]Color{
+				{Red: 255},
+				{Red: 255, Green: 255},
+				{Green: 255},
+				{Green: 255, Blue: 255},
+				{Blue: 255},
+				{Red: 255, Blue: 255},
+			},
+			PaletteSize:    6,
+			Mode:           PaletteFade,
+			ColorShift:     1,
+			Speed:          500,
+			GeneralPurpose: 255,
+		}
+	}
+
+	return memory
+}
+
+// Active returns the field set that is currently displayed by the Boxi.
+func (memory ArduinoMemory) Active(boxi LightingTarget) LightingFieldSet {
+	index := boxi.index()
+	return memory.fieldSets[index][memory.activeField[index]]
+}
+
+// Staged returns the field set of the Boxi that is written to by incoming lighting fields.
+func (memory ArduinoMemory) Staged(boxi LightingTarget) LightingFieldSet {
+	index := boxi.index()
+	return memory.fieldSets[index][1-memory.activeField[index]]
+}
+
+// Write applies a single bus message to the memory. It returns the Boxis whose lighting was applied.
+// Messages inside a transaction are held back until the transaction is committed.
+func (memory *ArduinoMemory) Write(message BusMessage) (LightingTarget, error) {
+	if err := validateMessage(message); err != nil {
+		return 0, err
+	}
+
+	switch message.field {
+	case TransactionBegin:
+		//An uncommitted transaction gets discarded
+		transactionId := message.payload[0]
+		memory.transactionId = &transactionId
+		memory.transaction = nil
+		return 0, nil
+	case TransactionCommit:
+		transaction := memory.transaction
+		isComplete := memory.transactionId != nil && *memory.transactionId == message.payload[0] &&
+			len(transaction) == int(message.payload[1])
+		memory.transactionId = nil
+		memory.transaction = nil
+
+		if !isComplete {
+			return 0, fmt.Errorf("transaction 0x%02x is incomplete and was discarded", message.payload[0])
+		}
+
+		var applied LightingTarget
+		for _, transactionMessage := range transaction {
+			applied |= memory.writeField(transactionMessage)
+		}
+		return applied, nil
+	}
+
+	if memory.transactionId != nil {
+		memory.transaction = append(memory.transaction, message)
+		return 0, nil
+	}
+
+	return memory.writeField(message), nil
+}
+
+func validateMessage(message BusMessage) error {
+	expectedLen, ok := getPayloadLength(message.field)
+	if !ok {
+		return fmt.Errorf("unknown memory field 0x%02x", byte(message.field))
+	}
+
+	if len(message.payload) != expectedLen {
+		return fmt.Errorf("memory field 0x%02x expects %d bytes, but payload is %d bytes", byte(message.field), expectedLen, len(message.payload))
+	}
+
+	return nil
+}
+
+// writeField writes a validated message into the memory. It returns the Boxis whose lighting was applied.
+func (memory *ArduinoMemory) writeField(message BusMessage) LightingTarget {
+	payload := message.payload
+
+	switch message.field {
+	case StatusCode:
+		memory.StatusCode = DisplayStatusCode(payload[0])
+		memory.StatusServerId = payload[1]
+		return 0
+	case LightingApply:
+		target := memory.target
+		memory.paletteOffset = 0
+		memory.target = TargetBothBoxis
+		if payload[0] != 0 {
+			memory.ApplyOnNextBeat |= target
+			return 0
+		}
+
+		memory.ApplyOnNextBeat &^= target
+		memory.apply(target)
+		return target
+	case LightingSelectTarget:
+		memory.target = LightingTarget(payload[0]) & TargetBothBoxis
+		return 0
+	case LightingPaletteOffset:
+		memory.paletteOffset = payload[0]
+		return 0
+	case EnableInternalLights:
+		memory.InternalLeds = payload[0] > 0
+		return 0
+	case ProtocolVersion, TransactionBegin, TransactionCommit:
+		//Handled by the bus, not stored in memory
+		return 0
+	}
+
+	for boxi := range memory.fieldSets {
+		if memory.target&(1<<boxi) != 0 {
+			writeLightingField(&memory.fieldSets[boxi][1-memory.activeField[boxi]], message, memory.paletteOffset)
+		}
+	}
+
+	return 0
+}
+
+// writeLightingField writes a validated lighting field into a field set.
+func writeLightingField(staged *LightingFieldSet, message BusMessage, paletteOffset byte) {
+	payload := message.payload
+
+	switch message.field {
+	case LightingMode:
+		staged.Mode = LightingModeId(payload[0])
+	case LightingSpeed:
+		staged.Speed = uint16(payload[0])<<8 | uint16(payload[1])
+	case LightingPaletteSize:
+		staged.PaletteSize = min(payload[0], MaxPaletteSize)
+	case LightingColorShift:
+		staged.ColorShift = payload[0]
+	case LightingGeneralPurpose:
+		staged.GeneralPurpose = payload[0]
+	default:
+		index := int(paletteOffset) + int(message.field-LightingPaletteA)
+		if index >= MaxPaletteSize {
+			return
+		}
+
+		staged.Palette[index] = Color{
+			Red:         payload[0],
+			Green:       payload[1],
+			Blue:        payload[2],
+			White:       payload[3],
+			Amber:       payload[4],
+			UltraViolet: payload[5],
+		}
+	}
+}
+
+// Beat signals a beat to the memory. It returns the Boxis whose pending lighting change was applied.
+func (memory *ArduinoMemory) Beat() LightingTarget {
+	applied := memory.ApplyOnNextBeat
+	memory.ApplyOnNextBeat = 0
+	memory.apply(applied)
+	return applied
+}
+
+func (memory *ArduinoMemory) apply(target LightingTarget) {
+	for boxi := range memory.activeField {
+		if target&(1<<boxi) != 0 {
+			memory.activeField[boxi] = 1 - memory.activeField[boxi]
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package BoxiBus
+
+import (
+	"testing"
+)
+
+// writeAll writes the messages into the memory and returns the Boxis whose lighting was applied and the first error.
+func writeAll(memory *ArduinoMemory, messages ...BusMessage) (LightingTarget, error) {
+	var applied LightingTarget
+	var firstErr error
+	for _, message := range messages {
+		target, err := memory.Write(message)
+		applied |= target
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return applied, firstErr
+}
+
+func TestArduinoMemoryTransactions(t *testing.T) {
+	green, blue := Color{Green: 255}, Color{Blue: 255}
+	setColor := CreateLightingSetColor(green, blue, false)
+	off := CreateLightingOff(false)
+	initial := CreateArduinoMemory().Active(TargetBoxi1)
+
+	tests := []struct {
+		name          string
+		messages      []BusMessage
+		expectError   bool
+		expectApplied LightingTarget
+		expectMode    LightingModeId
+		expectColor   Color //The first palette color of the active field set
+	}{
+		{
+			name:          "committed",
+			messages:      append(append([]BusMessage{beginTransaction(1)}, setColor...), commitTransaction(1, len(setColor))),
+			expectApplied: TargetBothBoxis,
+			expectMode:    SetColor,
+			expectColor:   green,
+		},
+		{
+			name: "aborted by the next transaction",
+			messages: append(append(append([]BusMessage{beginTransaction(1)}, setColor[:3]...), beginTransaction(2)),
+				append(off, commitTransaction(2, len(off)))...),
+			expectApplied: TargetBothBoxis,
+			expectMode:    Off,
+			expectColor:   Color{},
+		},
+		{
+			name: "cut short by a dropped frame",
+			messages: append(append(append([]BusMessage{beginTransaction(1)}, setColor[:1]...), setColor[2:]...),
+				commitTransaction(1, len(setColor))),
+			expectError: true,
+			expectMode:  initial.Mode,
+			expectColor: initial.Palette[0],
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			memory := CreateArduinoMemory()
+			applied, err := writeAll(memory, test.messages...)
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if applied != test.expectApplied {
+				t.Errorf("lighting of Boxis %d applied, expected %d", applied, test.expectApplied)
+			}
+
+			for _, boxi := range []LightingTarget{TargetBoxi1, TargetBoxi2} {
+				active := memory.Active(boxi)
+				if active.Mode != test.expectMode || active.Palette[0] != test.expectColor {
+					t.Errorf("Boxi %d is running %s, expected mode %s with %s", boxi, active, test.expectMode, test.expectColor)
+				}
+			}
+
+			//Nothing of a discarded transaction may leak into the next lighting
+			if staged := memory.Staged(TargetBoxi1); staged.Palette[0] == green && test.expectMode != SetColor {
+				t.Errorf("messages of a discarded transaction were staged: %s", staged)
+			}
+		})
+	}
+}
+
+func TestArduinoMemoryTransactionAppliedOnBeat(t *testing.T) {
+	setColor := CreateLightingSetColor(Color{Red: 255}, Color{}, true)
+	memory := CreateArduinoMemory()
+
+	applied, err := writeAll(memory, append(append([]BusMessage{beginTransaction(7)}, setColor...), commitTransaction(7, len(setColor)))...)
+	if err != nil || applied != 0 {
+		t.Fatalf("lighting applied before the beat, applied: %d, error: %v", applied, err)
+	}
+
+	if applied := memory.Beat(); applied != TargetBothBoxis || memory.Active(TargetBoxi1).Mode != SetColor {
+		t.Errorf("lighting not applied on the beat, applied: %d, active: %s", applied, memory.Active(TargetBoxi1))
+	}
+}
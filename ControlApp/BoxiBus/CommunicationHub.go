@@ -1,8 +1,13 @@
 package BoxiBus
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
 	"sync"
+	"time"
 
 	"go.bug.st/serial"
 )
@@ -22,20 +27,147 @@ const (
 	LightingPaletteSize    MemoryField = 0x07
 	LightingPaletteA       MemoryField = 0x08
 	LightingPaletteB       MemoryField = 0x09
+	LightingPaletteH       MemoryField = 0x0F
 	EnableInternalLights   MemoryField = 0x10
+	ProtocolVersion        MemoryField = 0x11
+	TransactionBegin       MemoryField = 0x12
+	TransactionCommit      MemoryField = 0x13
+	LightingPaletteOffset  MemoryField = 0x14
+	LightingSelectTarget   MemoryField = 0x15
 )
 
+// Arduino's telemetry fields sent back to the host
+const (
+	LightingApplied       MemoryField = 0x81
+	StatusReport          MemoryField = 0x82
+	BeatPulse             MemoryField = 0x83
+	BrightnessReport      MemoryField = 0x84
+	FrameAcknowledge      MemoryField = 0x85
+	FrameRejected         MemoryField = 0x86
+	ProtocolVersionReport MemoryField = 0x87
+)
+
+// Protocol versions and the features they introduced. The lightshow_v3 firmware predates the negotiation and
+// only speaks v1, it neither acknowledges frames nor sends telemetry, as its UART transmit line drives Boxi 2.
+// The later versions are implemented by the Simulator only, the hub falls back to v1 if the Arduino doesn't
+// report its version. The features of the later versions are then left out, see the README.
+const (
+	framingProtocolVersion     = 2 //Checksummed and sequence-numbered v2 framing
+	telemetryProtocolVersion   = 2 //Applied lighting, beats, brightness and status codes are reported back
+	transactionProtocolVersion = 3 //Message blocks are staged and applied all-or-nothing
+	palettePagingVersion       = 4 //Palettes of up to 32 colors are written in pages of 8
+	perBoxiLightingVersion     = 5 //Every Boxi runs its own lighting program
+	extendedModesVersion       = 6 //Chase, rainbow cycle and breathing modes
+	latestProtocolVersion      = extendedModesVersion
+)
+
+const (
+	telemetryBufferSize = 8
+	sendQueueSize       = 16
+	negotiationTimeout  = 250 * time.Millisecond
+	frameAckTimeout     = 50 * time.Millisecond
+	maxRetransmits      = 3
+	minReconnectBackoff = 500 * time.Millisecond
+	maxReconnectBackoff = 10 * time.Second
+)
+
+// ErrNotConnected is returned by Send while the UART is lost and the hub is trying to reopen it.
+var ErrNotConnected = errors.New("the Arduino is not connected")
+
+var errSendQueueFull = errors.New("too many message blocks are waiting to be sent to the Arduino")
+
 type BusMessage struct {
 	field   MemoryField
 	payload []byte
 }
 
+func (message BusMessage) Field() MemoryField {
+	return message.field
+}
+
+func (message BusMessage) Payload() []byte {
+	return message.payload
+}
+
 type CommunicationHub struct {
-	lock       *sync.Mutex
-	connection serial.Port
+	lock             *sync.Mutex
+	busLock          *sync.Mutex //Held while writing to the bus and waiting for the Arduino, guards the sequence and transaction ids
+	queue            chan MessageBlock
+	device           string
+	mode             *serial.Mode
+	connection       serial.Port
+	connected        bool
+	closed           bool
+	connectionSince  time.Time
+	reconnects       int
+	lastError        error
+	lastLighting     [2]MessageBlock //The last lighting program of each Boxi, replayed after reconnecting
+	lastInternalLeds MessageBlock    //The last internal LED config, replayed after reconnecting
+	protocolVersion  byte
+	sequence         byte
+	transactionId    byte
+	frameResults     chan BusMessage
+	versionReports   chan byte
+	telemetry        telemetryChannels
+	Acknowledgements <-chan LightingModeId    //Reports the lighting modes that took effect on the Arduino
+	StatusCodes      <-chan DisplayStatusCode //Reports the status code currently displayed by the Arduino
+	Beats            <-chan time.Time         //Reports the beat pulses detected at D7
+	Brightness       <-chan float64           //Reports the master brightness set by the potentiometer at A6
 }
 
-func ConnectToArduino(baudRate int) (*CommunicationHub, error) {
+// telemetryChannels are the sending ends of the telemetry channels. They outlive the UART, so listeners
+// keep receiving telemetry after the hub reconnected.
+type telemetryChannels struct {
+	acknowledgements chan<- LightingModeId
+	statusCodes      chan<- DisplayStatusCode
+	beats            chan<- time.Time
+	brightness       chan<- float64
+}
+
+// ConnectionState describes the state of the UART connection to the Arduino.
+type ConnectionState struct {
+	Device          string
+	Connected       bool
+	Since           time.Time //When the connection was last established or lost
+	ProtocolVersion byte
+	Reconnects      int   //How often the connection was reestablished after a failure
+	LastError       error //The error that caused the connection to be lost, if any
+}
+
+// SupportsLightingMode returns whether the firmware of the Arduino knows the lighting mode.
+func (state ConnectionState) SupportsLightingMode(mode LightingModeId) bool {
+	return supportsLightingMode(state.ProtocolVersion, mode)
+}
+
+// supportsLightingMode returns whether firmware speaking the protocol version knows the lighting mode.
+// The firmware turns the lights off for modes it doesn't know.
+func supportsLightingMode(protocolVersion byte, mode LightingModeId) bool {
+	return mode <= Strobe || protocolVersion >= extendedModesVersion && mode <= Breathing
+}
+
+// SupportsPerBoxiLighting returns whether the firmware of the Arduino runs a separate lighting program for each Boxi.
+func (state ConnectionState) SupportsPerBoxiLighting() bool {
+	return state.ProtocolVersion >= perBoxiLightingVersion
+}
+
+// MaxPaletteSize returns the most colors a palette can have on the firmware of the Arduino. Firmware without
+// palette paging only knows the palette fields, it shows the first colors of longer palettes.
+func (state ConnectionState) MaxPaletteSize() int {
+	if state.ProtocolVersion < palettePagingVersion {
+		return palettePageSize
+	}
+
+	return MaxPaletteSize
+}
+
+// HasTelemetry returns whether the Arduino reports telemetry, like the lighting modes that took effect.
+func (state ConnectionState) HasTelemetry() bool {
+	return state.Connected && state.ProtocolVersion >= telemetryProtocolVersion
+}
+
+// ConnectToArduino opens the UART at the given device path and returns a hub to communicate with the Arduino.
+// If the UART can't be opened or fails later on, the hub keeps trying to reopen it in the background.
+func ConnectToArduino(device string, baudRate int) *CommunicationHub {
 
 	mode := &serial.Mode{
 		BaudRate: baudRate,
@@ -44,44 +176,431 @@ func ConnectToArduino(baudRate int) (*CommunicationHub, error) {
 		StopBits: serial.OneStopBit,
 	}
 
-	port, err := serial.Open("/dev/ttyAMA0", mode)
+	acknowledgements := make(chan LightingModeId, telemetryBufferSize)
+	statusCodes := make(chan DisplayStatusCode, telemetryBufferSize)
+	beats := make(chan time.Time, telemetryBufferSize)
+	brightness := make(chan float64, telemetryBufferSize)
+
+	hub := &CommunicationHub{
+		lock:             &sync.Mutex{},
+		busLock:          &sync.Mutex{},
+		queue:            make(chan MessageBlock, sendQueueSize),
+		device:           device,
+		mode:             mode,
+		connectionSince:  time.Now(),
+		protocolVersion:  1,
+		frameResults:     make(chan BusMessage, telemetryBufferSize),
+		versionReports:   make(chan byte, 1),
+		telemetry:        telemetryChannels{acknowledgements, statusCodes, beats, brightness},
+		Acknowledgements: acknowledgements,
+		StatusCodes:      statusCodes,
+		Beats:            beats,
+		Brightness:       brightness,
+	}
+
+	go hub.transmit()
+
+	port, err := serial.Open(device, mode)
+	if err != nil {
+		log.Printf("Failed to open UART %s, retrying in the background: %s", device, err)
+		hub.lastError = err
+		go hub.reconnect()
+		return hub
+	}
+
+	hub.connection = port
+	hub.connected = true
+
+	go hub.receive(port)
+	hub.negotiateProtocol()
+	return hub
+}
+
+// markDisconnected closes the failed UART and starts reopening it. Requires the lock.
+func (hub *CommunicationHub) markDisconnected(err error) {
+	if !hub.connected {
+		return
+	}
+
+	log.Printf("Connection to the Arduino at %s lost: %s", hub.device, err)
+	_ = hub.connection.Close()
+	hub.connection = nil
+	hub.connected = false
+	hub.connectionSince = time.Now()
+	hub.lastError = err
+
+	go hub.reconnect()
+}
+
+// Close closes the UART and stops reconnecting. The hub can't be used afterwards.
+func (hub *CommunicationHub) Close() error {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	if hub.closed {
+		return nil
+	}
+
+	//Send doesn't queue blocks once the hub is disconnected, so the queue can be closed
+	hub.closed = true
+	close(hub.queue)
+	if !hub.connected {
+		return nil
+	}
+
+	hub.connected = false
+	return hub.connection.Close()
+}
+
+// reconnect reopens the UART with an exponential backoff. Once connected, the protocol is negotiated
+// again and the last lighting state is restored, as the Arduino might have been reset in the meantime.
+func (hub *CommunicationHub) reconnect() {
+	backoff := minReconnectBackoff
+	for {
+		time.Sleep(backoff)
+
+		hub.lock.Lock()
+		closed := hub.closed
+		hub.lock.Unlock()
+		if closed {
+			return
+		}
+
+		port, err := serial.Open(hub.device, hub.mode)
+		if err != nil {
+			hub.lock.Lock()
+			hub.lastError = err
+			hub.lock.Unlock()
+
+			backoff = min(backoff*2, maxReconnectBackoff)
+			continue
+		}
+
+		hub.lock.Lock()
+		if hub.closed {
+			hub.lock.Unlock()
+			_ = port.Close()
+			return
+		}
+		hub.connection = port
+		hub.connected = true
+		hub.connectionSince = time.Now()
+		hub.reconnects++
+		hub.protocolVersion = 1
+		hub.lock.Unlock()
+
+		log.Printf("Connection to the Arduino at %s reestablished", hub.device)
+		go hub.receive(port)
+		hub.negotiateProtocol()
+		hub.restoreState()
+		return
+	}
+}
+
+// restoreState replays the last internal LED config and lighting instruction.
+func (hub *CommunicationHub) restoreState() {
+	hub.lock.Lock()
+	blocks := []MessageBlock{hub.lastInternalLeds}
+	if hub.lastLighting[0] != nil && hub.lastLighting[1] != nil {
+		blocks = append(blocks, CreateLightingPerBoxi(hub.lastLighting[0], hub.lastLighting[1]))
+	}
+	hub.lock.Unlock()
+
+	for _, block := range blocks {
+		if len(block) == 0 {
+			continue
+		}
+
+		if err := hub.Send(block); err != nil {
+			log.Printf("Restoring the lighting state failed: %s", err)
+			return
+		}
+	}
+}
+
+// negotiateProtocol asks the Arduino for the newest framing it understands. Old firmware ignores
+// the request, in which case the hub keeps using the v1 framing.
+func (hub *CommunicationHub) negotiateProtocol() {
+	hub.busLock.Lock()
+	defer hub.busLock.Unlock()
+
+	//Drop reports left over from a previous connection
+	select {
+	case <-hub.versionReports:
+	default:
+	}
+
+	err := hub.sendSingleMessage(BusMessage{ProtocolVersion, []byte{latestProtocolVersion}}, hub.GetProtocolVersion())
+	if err != nil {
+		log.Printf("Protocol negotiation failed, using BoxiBus protocol v%d: %s", hub.GetProtocolVersion(), err)
+		return
+	}
+
+	select {
+	case version := <-hub.versionReports:
+		hub.lock.Lock()
+		hub.protocolVersion = max(min(version, latestProtocolVersion), 1)
+		hub.lock.Unlock()
+	case <-time.After(negotiationTimeout):
+	}
+
+	log.Printf("Using BoxiBus protocol v%d", hub.GetProtocolVersion())
+}
+
+// GetProtocolVersion returns the protocol version negotiated with the Arduino.
+func (hub *CommunicationHub) GetProtocolVersion() byte {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	return hub.protocolVersion
+}
+
+// GetConnectionState returns the current state of the UART connection.
+func (hub *CommunicationHub) GetConnectionState() ConnectionState {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	return ConnectionState{
+		Device:          hub.device,
+		Connected:       hub.connected,
+		Since:           hub.connectionSince,
+		ProtocolVersion: hub.protocolVersion,
+		Reconnects:      hub.reconnects,
+		LastError:       hub.lastError,
+	}
+}
+
+// receive parses the telemetry sent back by the Arduino. Values nobody is listening for are dropped.
+// Firmware speaking v1 sends none, so the channels stay silent.
+func (hub *CommunicationHub) receive(port serial.Port) {
+	reader := newTelemetryReader(port)
+	acknowledgements, statusCodes, beats, brightness := hub.telemetry.acknowledgements, hub.telemetry.statusCodes,
+		hub.telemetry.beats, hub.telemetry.brightness
+
+	for {
+		message, err := reader.readMessage()
+		if err != nil {
+			//Errors of a port that was already replaced are expected
+			hub.lock.Lock()
+			if hub.connection == port {
+				hub.markDisconnected(fmt.Errorf("receiving from UART failed: %w", err))
+			}
+			hub.lock.Unlock()
+			return
+		}
+
+		switch message.field {
+		case LightingApplied:
+			select {
+			case acknowledgements <- LightingModeId(message.payload[0]):
+			default:
+			}
+		case StatusReport:
+			select {
+			case statusCodes <- DisplayStatusCode(message.payload[0]):
+			default:
+			}
+		case BeatPulse:
+			select {
+			case beats <- time.Now():
+			default:
+			}
+		case BrightnessReport:
+			value := float64(uint16(message.payload[0])<<8|uint16(message.payload[1])) / 1000
+			select {
+			case brightness <- min(value, 1):
+			default:
+			}
+		case FrameAcknowledge, FrameRejected:
+			select {
+			case hub.frameResults <- message:
+			default:
+			}
+		case ProtocolVersionReport:
+			select {
+			case hub.versionReports <- message.payload[0]:
+			default:
+			}
+		}
+	}
+}
+
+// sendSingleMessage writes a message in the framing of the given protocol version. Requires the bus lock.
+func (hub *CommunicationHub) sendSingleMessage(message BusMessage, protocolVersion byte) error {
+	if protocolVersion < framingProtocolVersion {
+		sendBuffer, err := encodeMessage(message)
+		if err != nil {
+			return err
+		}
+
+		return hub.write(sendBuffer)
+	}
+
+	hub.sequence++
+	sendBuffer, err := encodeMessageV2(message, hub.sequence)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open UART: %w", err)
+		return err
 	}
 
-	sendMutex := sync.Mutex{}
+	// Retransmit the frame until the Arduino acknowledges it. Duplicates are detected by their sequence number.
+	for attempt := 0; attempt <= maxRetransmits; attempt++ {
+		if err := hub.write(sendBuffer); err != nil {
+			return err
+		}
+
+		if hub.waitForFrameResult(hub.sequence) {
+			return nil
+		}
+	}
 
-	return &CommunicationHub{
-		&sendMutex,
-		port,
-	}, nil
+	return fmt.Errorf("frame %d wasn't acknowledged after %d attempts", hub.sequence, maxRetransmits+1)
 }
 
-func (hub *CommunicationHub) sendSingleMessage(message BusMessage) error {
-	payloadLen := len(message.payload)
-	if payloadLen > 6 {
-		return fmt.Errorf("the payload length cannot exceed 6 bytes, but payload is %d bytes", payloadLen)
+// write writes raw bytes to the UART and starts reconnecting if that fails.
+func (hub *CommunicationHub) write(buffer []byte) error {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	if !hub.connected {
+		return ErrNotConnected
 	}
 
-	sendBuffer := make([]byte, payloadLen+4)
-	sendBuffer[0] = 0x55
-	sendBuffer[1] = 0x77
-	sendBuffer[2] = 0x4f
-	sendBuffer[3] = byte(message.field)
-	for i := 0; i < len(message.payload); i++ {
-		sendBuffer[4+i] = message.payload[i]
+	if _, err := hub.connection.Write(buffer); err != nil {
+		hub.markDisconnected(err)
+		return fmt.Errorf("writing to UART failed: %w", err)
 	}
 
-	_, err := hub.connection.Write(sendBuffer)
-	return err
+	return nil
 }
 
+// waitForFrameResult returns whether the frame with the given sequence number was acknowledged in time.
+func (hub *CommunicationHub) waitForFrameResult(sequence byte) bool {
+	timeout := time.After(frameAckTimeout)
+	for {
+		select {
+		case result := <-hub.frameResults:
+			//Skip late results of earlier frames
+			if result.payload[0] != sequence {
+				continue
+			}
+
+			return result.field == FrameAcknowledge
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+// Send queues the block to be transmitted to the Arduino. It doesn't wait for the transmission, errors while
+// writing are logged instead. Lighting instructions and the internal LED config are remembered even if sending
+// fails, so they can be restored once the connection is back.
 func (hub *CommunicationHub) Send(block MessageBlock) error {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	for _, message := range block {
+		if message.field != LightingMode {
+			continue
+		}
+
+		if mode := LightingModeId(message.payload[0]); !supportsLightingMode(hub.protocolVersion, mode) {
+			return fmt.Errorf("the %s lighting mode isn't supported by BoxiBus protocol v%d", mode, hub.protocolVersion)
+		}
+	}
+
+	if block.containsField(LightingApply) {
+		boxi1, boxi2 := block.lightingPrograms()
+		if boxi1 != nil {
+			hub.lastLighting[0] = boxi1
+		}
+		if boxi2 != nil {
+			hub.lastLighting[1] = boxi2
+		}
+	}
+	if block.containsField(EnableInternalLights) {
+		hub.lastInternalLeds = block
+	}
+
+	if !hub.connected {
+		return ErrNotConnected
+	}
+
+	select {
+	case hub.queue <- block:
+		return nil
+	default:
+		return errSendQueueFull
+	}
+}
+
+// transmit writes the queued blocks to the Arduino one after the other. It runs for the lifetime of the hub,
+// so waiting for the Arduino to acknowledge frames never blocks the callers of Send.
+func (hub *CommunicationHub) transmit() {
+	for block := range hub.queue {
+		err := hub.sendBlock(block)
+		if err != nil && !errors.Is(err, ErrNotConnected) {
+			log.Printf("Error sending message block to the Arduino: %s", err)
+		}
+	}
+}
+
+// sendBlock writes the block in the negotiated protocol version. If supported by the firmware, the block is
+// wrapped in a transaction, so it only takes effect once all of its messages were received.
+func (hub *CommunicationHub) sendBlock(block MessageBlock) error {
+	hub.busLock.Lock()
+	defer hub.busLock.Unlock()
+
+	protocolVersion := hub.GetProtocolVersion()
+	if protocolVersion < palettePagingVersion {
+		if paletteBlock := block.withoutPalettePages(); len(paletteBlock) != len(block) {
+			log.Printf("BoxiBus protocol v%d doesn't support palette paging, only the first %d colors of the palette are sent", protocolVersion, palettePageSize)
+			block = paletteBlock
+		}
+	}
+	if protocolVersion < perBoxiLightingVersion {
+		if boxi1, boxi2 := block.lightingPrograms(); boxi2 != nil && !boxi2.equals(boxi1) {
+			log.Printf("BoxiBus protocol v%d doesn't support lighting per Boxi, both Boxis run the lighting of Boxi 1", protocolVersion)
+		}
+		block = block.withoutBoxiTargets()
+	}
+
+	useTransaction := protocolVersion >= transactionProtocolVersion && len(block) > 1
+	if useTransaction {
+		hub.transactionId++
+		if err := hub.sendSingleMessage(BusMessage{TransactionBegin, []byte{hub.transactionId}}, protocolVersion); err != nil {
+			return err
+		}
+	}
+
 	for _, message := range block {
-		if err := hub.sendSingleMessage(message); err != nil {
+		if err := hub.sendSingleMessage(message, protocolVersion); err != nil {
+			return err
+		}
+	}
+
+	if useTransaction {
+		commitMessage := BusMessage{TransactionCommit, []byte{hub.transactionId, byte(len(block))}}
+		if err := hub.sendSingleMessage(commitMessage, protocolVersion); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// equals returns whether both blocks consist of the same messages.
+func (block MessageBlock) equals(other MessageBlock) bool {
+	return slices.EqualFunc(block, other, func(a BusMessage, b BusMessage) bool {
+		return a.field == b.field && bytes.Equal(a.payload, b.payload)
+	})
+}
+
+func (block MessageBlock) containsField(field MemoryField) bool {
+	for _, message := range block {
+		if message.field == field {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,207 @@
+package BoxiBus
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+const peerTimeout = 2 * time.Second
+
+// testPeer plays the Arduino on the other end of a pseudo-terminal. Every frame the hub writes is passed to
+// the respond function, whose telemetry is sent back.
+type testPeer struct {
+	master  *os.File
+	frames  chan frame
+	respond func(received frame) []BusMessage
+}
+
+// connectToPeer opens a pseudo-terminal, starts the peer on its master end and connects a hub to its slave end.
+func connectToPeer(t *testing.T, respond func(received frame) []BusMessage) (*CommunicationHub, *testPeer) {
+	master, slave, err := openPseudoTerminal()
+	if err != nil {
+		t.Skipf("no pseudo-terminal available: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = slave.Close()
+		_ = master.Close()
+	})
+
+	peer := &testPeer{master: master, frames: make(chan frame, 64), respond: respond}
+	go peer.run()
+
+	hub := ConnectToArduino(slave.Name(), 38400)
+	t.Cleanup(func() {
+		_ = hub.Close()
+	})
+
+	return hub, peer
+}
+
+func (peer *testPeer) run() {
+	reader := newFrameReader(peer.master)
+	for {
+		received, err := reader.readFrame()
+		if err != nil && received.version == 0 {
+			return
+		}
+
+		peer.frames <- received
+		for _, message := range peer.respond(received) {
+			buffer, _ := encodeTelemetry(message)
+			_, _ = peer.master.Write(buffer)
+		}
+	}
+}
+
+// next returns the next frame written by the hub.
+func (peer *testPeer) next(t *testing.T) frame {
+	t.Helper()
+
+	select {
+	case received := <-peer.frames:
+		return received
+	case <-time.After(peerTimeout):
+		t.Fatal("the hub didn't write a frame in time")
+		return frame{}
+	}
+}
+
+// expectSilence checks that the hub doesn't write any further frame.
+func (peer *testPeer) expectSilence(t *testing.T) {
+	t.Helper()
+
+	select {
+	case received := <-peer.frames:
+		t.Fatalf("unexpected frame %s", received.BusMessage)
+	case <-time.After(4 * frameAckTimeout):
+	}
+}
+
+// respondAsVersion answers like firmware speaking the protocol version, acknowledging every v2 frame.
+func respondAsVersion(version byte) func(received frame) []BusMessage {
+	return func(received frame) []BusMessage {
+		var responses []BusMessage
+		if received.version >= framingProtocolVersion {
+			responses = append(responses, BusMessage{FrameAcknowledge, []byte{received.sequence}})
+		}
+		if received.field == ProtocolVersion {
+			responses = append(responses, BusMessage{ProtocolVersionReport, []byte{version}})
+		}
+
+		return responses
+	}
+}
+
+func TestNegotiationFallsBackToV1(t *testing.T) {
+	hub, peer := connectToPeer(t, func(received frame) []BusMessage {
+		return nil
+	})
+
+	request := peer.next(t)
+	if request.version != 1 || request.field != ProtocolVersion {
+		t.Fatalf("negotiation started with %s in v%d framing", request.BusMessage, request.version)
+	}
+	if version := hub.GetProtocolVersion(); version != 1 {
+		t.Fatalf("negotiated v%d with a silent peer", version)
+	}
+
+	if err := hub.Send(CreateLightingOff(false)); err != nil {
+		t.Fatalf("sending failed: %s", err)
+	}
+	for _, field := range []MemoryField{LightingMode, LightingApply} {
+		if received := peer.next(t); received.version != 1 || received.field != field {
+			t.Fatalf("received %s in v%d framing, expected field 0x%02x in v1 framing", received.BusMessage, received.version, byte(field))
+		}
+	}
+}
+
+func TestNegotiationUsesReportedVersion(t *testing.T) {
+	hub, peer := connectToPeer(t, respondAsVersion(framingProtocolVersion))
+	peer.next(t)
+
+	if version := hub.GetProtocolVersion(); version != framingProtocolVersion {
+		t.Fatalf("negotiated v%d, expected v%d", version, framingProtocolVersion)
+	}
+
+	if err := hub.Send(CreateConfigInternalLeds(true)); err != nil {
+		t.Fatalf("sending failed: %s", err)
+	}
+	if received := peer.next(t); received.version != framingProtocolVersion || received.field != EnableInternalLights {
+		t.Fatalf("received %s in v%d framing", received.BusMessage, received.version)
+	}
+}
+
+func TestRejectedFrameIsResent(t *testing.T) {
+	rejected := false
+	hub, peer := connectToPeer(t, func(received frame) []BusMessage {
+		if received.field == LightingMode && !rejected {
+			rejected = true
+			return []BusMessage{{FrameRejected, []byte{received.sequence}}}
+		}
+
+		return respondAsVersion(framingProtocolVersion)(received)
+	})
+	peer.next(t)
+
+	if err := hub.Send(CreateLightingOff(true)); err != nil {
+		t.Fatalf("sending failed: %s", err)
+	}
+
+	first := peer.next(t)
+	resent := peer.next(t)
+	if first.field != LightingMode || resent.field != LightingMode || resent.sequence != first.sequence {
+		t.Fatalf("received %s (sequence %d) and %s (sequence %d), expected the rejected frame to be resent",
+			first.BusMessage, first.sequence, resent.BusMessage, resent.sequence)
+	}
+
+	if apply := peer.next(t); apply.field != LightingApply || apply.sequence != first.sequence+1 {
+		t.Fatalf("received %s (sequence %d) after the resent frame", apply.BusMessage, apply.sequence)
+	}
+	peer.expectSilence(t)
+}
+
+func TestUnacknowledgedFrameIsGivenUp(t *testing.T) {
+	negotiated := false
+	hub, peer := connectToPeer(t, func(received frame) []BusMessage {
+		//Acknowledge nothing after the negotiation
+		if negotiated {
+			return nil
+		}
+
+		negotiated = true
+		return respondAsVersion(framingProtocolVersion)(received)
+	})
+	peer.next(t)
+
+	if err := hub.sendBlock(CreateConfigInternalLeds(true)); err == nil {
+		t.Fatal("sending succeeded without an acknowledgement")
+	}
+
+	first := peer.next(t)
+	for attempt := 1; attempt <= maxRetransmits; attempt++ {
+		if received := peer.next(t); received.sequence != first.sequence {
+			t.Fatalf("attempt %d was sent with sequence %d instead of %d", attempt, received.sequence, first.sequence)
+		}
+	}
+	peer.expectSilence(t)
+}
+
+func TestSequenceRollsOver(t *testing.T) {
+	hub, peer := connectToPeer(t, respondAsVersion(framingProtocolVersion))
+	peer.next(t)
+
+	hub.busLock.Lock()
+	hub.sequence = 254
+	hub.busLock.Unlock()
+
+	if err := hub.sendBlock(CreateLightingSetColor(Color{Red: 255}, Color{Blue: 255}, false)); err != nil {
+		t.Fatalf("sending failed: %s", err)
+	}
+
+	for _, expected := range []byte{255, 0, 1, 2} {
+		if received := peer.next(t); received.sequence != expected {
+			t.Fatalf("received %s with sequence %d, expected %d", received.BusMessage, received.sequence, expected)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package BoxiBus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decode parses a captured byte stream sent to the Arduino into the messages it consists of.
+// It is the counterpart of the Create* builders. Frames with a bad checksum and retransmitted
+// frames are skipped, just like the firmware does.
+func Decode(capture []byte) ([]BusMessage, error) {
+	reader := newFrameReader(bytes.NewReader(capture))
+
+	var messages []BusMessage
+	var lastSequence *byte
+
+	for {
+		received, err := reader.readFrame()
+		if errors.Is(err, io.EOF) {
+			return messages, nil
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			return messages, errors.New("capture ends in the middle of a frame")
+		} else if errors.Is(err, errInvalidChecksum) {
+			continue
+		} else if err != nil {
+			return messages, err
+		}
+
+		//Skip retransmitted frames
+		if received.version >= framingProtocolVersion {
+			if lastSequence != nil && *lastSequence == received.sequence {
+				continue
+			}
+			lastSequence = &received.sequence
+		}
+
+		messages = append(messages, received.BusMessage)
+	}
+}
+
+// DecodeBlocks splits a captured byte stream sent to the Arduino back into the message blocks it consists of.
+// Transactions are returned as one block if they were committed completely and dropped otherwise, just like the
+// firmware does. Outside of transactions, a block ends with the message that makes the Arduino act on it.
+func DecodeBlocks(capture []byte) ([]MessageBlock, error) {
+	messages, err := Decode(capture)
+
+	var blocks []MessageBlock
+	var current MessageBlock
+	var transactionId *byte
+
+	for _, message := range messages {
+		switch message.field {
+		case TransactionBegin:
+			transactionId = &message.payload[0]
+			current = nil
+		case TransactionCommit:
+			if transactionId != nil && *transactionId == message.payload[0] && len(current) == int(message.payload[1]) {
+				blocks = append(blocks, current)
+			}
+			transactionId = nil
+			current = nil
+		default:
+			current = append(current, message)
+			if transactionId == nil && isBlockTerminator(message.field) {
+				blocks = append(blocks, current)
+				current = nil
+			}
+		}
+	}
+
+	if err != nil {
+		return blocks, err
+	}
+
+	if len(current) > 0 {
+		return blocks, fmt.Errorf("capture ends with %d messages that were never applied", len(current))
+	}
+
+	return blocks, nil
+}
+
+// isBlockTerminator returns whether the field makes the Arduino act on the messages received so far.
+func isBlockTerminator(field MemoryField) bool {
+	switch field {
+	case LightingApply, StatusCode, EnableInternalLights, ProtocolVersion:
+		return true
+	}
+
+	return false
+}
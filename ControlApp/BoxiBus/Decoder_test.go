@@ -0,0 +1,115 @@
+package BoxiBus
+
+import (
+	"reflect"
+	"testing"
+)
+
+// encodeCapture frames the messages in the v2 framing, as they would be captured on the bus.
+func encodeCapture(t *testing.T, messages ...BusMessage) []byte {
+	var capture []byte
+	for i, message := range messages {
+		frame, err := encodeMessageV2(message, byte(i))
+		if err != nil {
+			t.Fatalf("encoding %s failed: %s", message, err)
+		}
+		capture = append(capture, frame...)
+	}
+
+	return capture
+}
+
+func beginTransaction(id byte) BusMessage {
+	return BusMessage{TransactionBegin, []byte{id}}
+}
+
+func commitTransaction(id byte, length int) BusMessage {
+	return BusMessage{TransactionCommit, []byte{id, byte(length)}}
+}
+
+func TestDecodeBlocksTransactions(t *testing.T) {
+	setColor := CreateLightingSetColor(Color{Red: 255}, Color{Blue: 255}, false)
+	off := CreateLightingOff(true)
+
+	tests := []struct {
+		name     string
+		messages []BusMessage
+		expected []MessageBlock
+	}{
+		{
+			name:     "committed",
+			messages: append(append([]BusMessage{beginTransaction(1)}, setColor...), commitTransaction(1, len(setColor))),
+			expected: []MessageBlock{setColor},
+		},
+		{
+			name: "aborted by the next transaction",
+			messages: append(append(append([]BusMessage{beginTransaction(1)}, setColor[:2]...), beginTransaction(2)),
+				append(off, commitTransaction(2, len(off)))...),
+			expected: []MessageBlock{off},
+		},
+		{
+			name: "cut short by a dropped frame",
+			messages: append(append(append([]BusMessage{beginTransaction(1)}, setColor[0]), setColor[2:]...),
+				commitTransaction(1, len(setColor))),
+			expected: nil,
+		},
+		{
+			name:     "commit of another transaction",
+			messages: append(append([]BusMessage{beginTransaction(1)}, setColor...), commitTransaction(2, len(setColor))),
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks, err := DecodeBlocks(encodeCapture(t, test.messages...))
+			if err != nil {
+				t.Fatalf("DecodeBlocks failed: %s", err)
+			}
+
+			if !reflect.DeepEqual(blocks, test.expected) {
+				t.Errorf("DecodeBlocks returned %v, expected %v", blocks, test.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeSkipsRetransmittedAndCorruptedFrames(t *testing.T) {
+	setColor := CreateLightingSetColor(Color{Green: 255}, Color{}, false)
+
+	var capture []byte
+	for i, message := range setColor {
+		frame, err := encodeMessageV2(message, byte(i))
+		if err != nil {
+			t.Fatalf("encoding %s failed: %s", message, err)
+		}
+
+		//Every frame is corrupted once, then sent twice
+		corrupted := append([]byte{}, frame...)
+		corrupted[len(corrupted)-1] ^= 0xff
+		capture = append(append(append(capture, corrupted...), frame...), frame...)
+	}
+
+	messages, err := Decode(capture)
+	if err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(MessageBlock(messages), setColor) {
+		t.Errorf("Decode returned %v, expected %v", messages, setColor)
+	}
+}
+
+func TestDecodeBlocksReportsTruncatedCapture(t *testing.T) {
+	setColor := CreateLightingSetColor(Color{Red: 255}, Color{}, false)
+	capture := encodeCapture(t, setColor...)
+
+	blocks, err := DecodeBlocks(capture[:len(capture)-3])
+	if err == nil {
+		t.Error("DecodeBlocks accepted a capture ending in the middle of a frame")
+	}
+
+	if len(blocks) != 0 {
+		t.Errorf("DecodeBlocks returned %v for a block that was never applied", blocks)
+	}
+}
@@ -0,0 +1,248 @@
+package BoxiBus
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Frame types following the 0x55 0x77 preamble
+const (
+	frameTypeV1        byte = 0x4f //Bare field and payload, the length is implied by the field
+	frameTypeV2        byte = 0x32 //Sequence number, field, explicit length, payload and CRC-8
+	frameTypeTelemetry byte = 0x61 //Telemetry sent back by the Arduino, from protocol v2 on
+)
+
+const (
+	maxPayloadLength = 6
+	crcPolynomial    = 0x07
+)
+
+var framePreamble = []byte{0x55, 0x77}
+
+var errInvalidChecksum = errors.New("frame checksum mismatch")
+
+type frame struct {
+	BusMessage
+	version  byte
+	sequence byte
+}
+
+// getPayloadLength returns the fixed payload length the Arduino expects for a memory field.
+func getPayloadLength(field MemoryField) (int, bool) {
+	switch field {
+	case LightingApply, LightingMode, LightingColorShift, LightingGeneralPurpose, LightingPaletteSize, EnableInternalLights,
+		ProtocolVersion, TransactionBegin, LightingPaletteOffset, LightingSelectTarget:
+		return 1, true
+	case StatusCode, LightingSpeed, TransactionCommit:
+		return 2, true
+	}
+
+	if field >= LightingPaletteA && field <= LightingPaletteH {
+		return 6, true
+	}
+
+	return 0, false
+}
+
+// getTelemetryPayloadLength returns the fixed payload length of a telemetry field sent by the Arduino.
+func getTelemetryPayloadLength(field MemoryField) (int, bool) {
+	switch field {
+	case BeatPulse:
+		return 0, true
+	case LightingApplied, FrameAcknowledge, FrameRejected, ProtocolVersionReport:
+		return 1, true
+	case StatusReport, BrightnessReport:
+		return 2, true
+	}
+
+	return 0, false
+}
+
+func encodeMessage(message BusMessage) ([]byte, error) {
+	return encodeFrame(frameTypeV1, message)
+}
+
+func encodeTelemetry(message BusMessage) ([]byte, error) {
+	return encodeFrame(frameTypeTelemetry, message)
+}
+
+func encodeFrame(frameType byte, message BusMessage) ([]byte, error) {
+	payloadLen := len(message.payload)
+	if payloadLen > maxPayloadLength {
+		return nil, fmt.Errorf("the payload length cannot exceed %d bytes, but payload is %d bytes", maxPayloadLength, payloadLen)
+	}
+
+	sendBuffer := make([]byte, 0, payloadLen+4)
+	sendBuffer = append(sendBuffer, framePreamble...)
+	sendBuffer = append(sendBuffer, frameType, byte(message.field))
+	sendBuffer = append(sendBuffer, message.payload...)
+	return sendBuffer, nil
+}
+
+// encodeMessageV2 frames the message with its sequence number, explicit length and checksum.
+func encodeMessageV2(message BusMessage, sequence byte) ([]byte, error) {
+	payloadLen := len(message.payload)
+	if payloadLen > maxPayloadLength {
+		return nil, fmt.Errorf("the payload length cannot exceed %d bytes, but payload is %d bytes", maxPayloadLength, payloadLen)
+	}
+
+	sendBuffer := make([]byte, 0, payloadLen+7)
+	sendBuffer = append(sendBuffer, framePreamble...)
+	sendBuffer = append(sendBuffer, frameTypeV2, sequence, byte(message.field), byte(payloadLen))
+	sendBuffer = append(sendBuffer, message.payload...)
+	sendBuffer = append(sendBuffer, calculateCrc8(sendBuffer[3:]))
+	return sendBuffer, nil
+}
+
+// calculateCrc8 calculates the CRC-8 (polynomial 0x07) of the data.
+func calculateCrc8(data []byte) byte {
+	var crc byte
+	for _, value := range data {
+		crc ^= value
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ crcPolynomial
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+// frameReader splits a raw byte stream into frames, resynchronizing on the frame preamble.
+type frameReader struct {
+	reader    *bufio.Reader
+	telemetry bool
+}
+
+// newFrameReader creates a reader for the messages sent to the Arduino.
+func newFrameReader(reader io.Reader) *frameReader {
+	return &frameReader{bufio.NewReader(reader), false}
+}
+
+// newTelemetryReader creates a reader for the messages sent back by the Arduino.
+func newTelemetryReader(reader io.Reader) *frameReader {
+	return &frameReader{bufio.NewReader(reader), true}
+}
+
+// readFrame returns the next frame of the stream. If the checksum of a v2 frame doesn't match,
+// the frame is returned together with errInvalidChecksum, so it can be rejected by its sequence number.
+func (reader *frameReader) readFrame() (frame, error) {
+	for {
+		frameType, err := reader.seekPreamble()
+		if err != nil {
+			return frame{}, err
+		}
+
+		switch {
+		case frameType == frameTypeTelemetry && reader.telemetry:
+			message, ok, err := reader.readFixedLengthMessage(getTelemetryPayloadLength)
+			if err != nil {
+				return frame{}, err
+			} else if ok {
+				return frame{message, 1, 0}, nil
+			}
+		case frameType == frameTypeV1 && !reader.telemetry:
+			message, ok, err := reader.readFixedLengthMessage(getPayloadLength)
+			if err != nil {
+				return frame{}, err
+			} else if ok {
+				return frame{message, 1, 0}, nil
+			}
+		case frameType == frameTypeV2 && !reader.telemetry:
+			result, ok, err := reader.readV2Frame()
+			if ok || err != nil {
+				return result, err
+			}
+		}
+	}
+}
+
+// readMessage returns the next valid message of the stream.
+func (reader *frameReader) readMessage() (BusMessage, error) {
+	for {
+		result, err := reader.readFrame()
+		if errors.Is(err, errInvalidChecksum) {
+			continue
+		}
+
+		return result.BusMessage, err
+	}
+}
+
+func (reader *frameReader) readFixedLengthMessage(payloadLength func(field MemoryField) (int, bool)) (BusMessage, bool, error) {
+	fieldByte, err := reader.reader.ReadByte()
+	if err != nil {
+		return BusMessage{}, false, err
+	}
+
+	//Unknown fields are skipped the same way the Arduino does
+	field := MemoryField(fieldByte)
+	payloadLen, ok := payloadLength(field)
+	if !ok {
+		return BusMessage{}, false, nil
+	}
+
+	payload := make([]byte, payloadLen)
+	if _, err := io.ReadFull(reader.reader, payload); err != nil {
+		return BusMessage{}, false, err
+	}
+
+	return BusMessage{field, payload}, true, nil
+}
+
+func (reader *frameReader) readV2Frame() (frame, bool, error) {
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(reader.reader, header); err != nil {
+		return frame{}, false, err
+	}
+
+	sequence, field, payloadLen := header[0], MemoryField(header[1]), int(header[2])
+	if payloadLen > maxPayloadLength {
+		return frame{}, false, nil
+	}
+
+	payload := make([]byte, payloadLen+1)
+	if _, err := io.ReadFull(reader.reader, payload); err != nil {
+		return frame{}, false, err
+	}
+
+	result := frame{BusMessage{field, payload[:payloadLen]}, 2, sequence}
+	if calculateCrc8(append(header, payload[:payloadLen]...)) != payload[payloadLen] {
+		return result, true, errInvalidChecksum
+	}
+
+	return result, true, nil
+}
+
+// seekPreamble skips the stream until the preamble and returns the frame type following it.
+func (reader *frameReader) seekPreamble() (byte, error) {
+	matched := 0
+	for {
+		value, err := reader.reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if matched == len(framePreamble) {
+			if value != framePreamble[0] {
+				return value, nil
+			}
+			matched = 1
+		} else if value == framePreamble[matched] {
+			matched++
+		} else if value == framePreamble[0] {
+			matched = 1
+		} else {
+			matched = 0
+		}
+	}
+}
+
+func (message BusMessage) String() string {
+	return fmt.Sprintf("{field: 0x%02x, payload: % x}", byte(message.field), message.payload)
+}
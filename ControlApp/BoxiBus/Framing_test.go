@@ -0,0 +1,54 @@
+package BoxiBus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestCalculateCrc8 checks the CRC-8 against the check value of the 0x07 polynomial.
+func TestCalculateCrc8(t *testing.T) {
+	if crc := calculateCrc8([]byte("123456789")); crc != 0xf4 {
+		t.Fatalf("calculated 0x%02x, expected 0xf4", crc)
+	}
+}
+
+func TestEncodeMessageV2(t *testing.T) {
+	message := BusMessage{LightingSpeed, []byte{0x01, 0xf4}}
+	encoded, err := encodeMessageV2(message, 7)
+	if err != nil {
+		t.Fatalf("encoding failed: %s", err)
+	}
+
+	//The checksum covers the sequence number, field, length and payload
+	expected := []byte{0x55, 0x77, frameTypeV2, 7, byte(LightingSpeed), 2, 0x01, 0xf4, calculateCrc8([]byte{7, byte(LightingSpeed), 2, 0x01, 0xf4})}
+	if !bytes.Equal(encoded, expected) {
+		t.Fatalf("encoded % x, expected % x", encoded, expected)
+	}
+
+	received, err := newFrameReader(bytes.NewReader(encoded)).readFrame()
+	if err != nil || received.sequence != 7 || received.field != LightingSpeed || !bytes.Equal(received.payload, message.payload) {
+		t.Fatalf("read back %s with sequence %d: %v", received.BusMessage, received.sequence, err)
+	}
+}
+
+func TestReadFrameDetectsFlippedBits(t *testing.T) {
+	encoded, err := encodeMessageV2(BusMessage{LightingPaletteA, []byte{255, 128, 0, 0, 0, 0}}, 42)
+	if err != nil {
+		t.Fatalf("encoding failed: %s", err)
+	}
+
+	//Every bit after the frame type, a flipped length byte may also make the frame incomplete
+	for i := 3 * 8; i < len(encoded)*8; i++ {
+		corrupted := bytes.Clone(encoded)
+		corrupted[i/8] ^= 1 << (i % 8)
+
+		received, err := newFrameReader(bytes.NewReader(corrupted)).readFrame()
+		if err == nil {
+			t.Fatalf("flipping bit %d went unnoticed, read %s", i, received.BusMessage)
+		}
+		if errors.Is(err, errInvalidChecksum) && received.sequence != 42 && i/8 != 3 {
+			t.Fatalf("flipping bit %d changed the sequence number of the rejected frame to %d", i, received.sequence)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package BoxiBus
 
-import "errors"
+import (
+	"fmt"
+)
 
 type DisplayStatusCode byte
 
@@ -12,6 +14,11 @@ const (
 
 type LightingModeId byte
 
+const (
+	MaxPaletteSize  = 32 //The most colors a palette can hold
+	palettePageSize = 8  //The number of palette fields, colors beyond are written in pages
+)
+
 // Lighting modes
 const (
 	Off                    LightingModeId = 0x00
@@ -22,6 +29,18 @@ const (
 	PaletteBrightnessFlash LightingModeId = 0x05
 	PaletteHueFlash        LightingModeId = 0x06
 	Strobe                 LightingModeId = 0x07
+	Chase                  LightingModeId = 0x08
+	RainbowCycle           LightingModeId = 0x09
+	Breathing              LightingModeId = 0x0A
+)
+
+// LightingTarget selects the Boxis a lighting program is written to.
+type LightingTarget byte
+
+const (
+	TargetBoxi1     LightingTarget = 0x01
+	TargetBoxi2     LightingTarget = 0x02
+	TargetBothBoxis                = TargetBoxi1 | TargetBoxi2
 )
 
 type Color struct {
@@ -33,6 +52,40 @@ type Color struct {
 	UltraViolet byte
 }
 
+func (mode LightingModeId) String() string {
+	switch mode {
+	case Off:
+		return "Off"
+	case SetColor:
+		return "SetColor"
+	case FadeToColor:
+		return "FadeToColor"
+	case PaletteFade:
+		return "PaletteFade"
+	case PaletteSwitch:
+		return "PaletteSwitch"
+	case PaletteBrightnessFlash:
+		return "PaletteBrightnessFlash"
+	case PaletteHueFlash:
+		return "PaletteHueFlash"
+	case Strobe:
+		return "Strobe"
+	case Chase:
+		return "Chase"
+	case RainbowCycle:
+		return "RainbowCycle"
+	case Breathing:
+		return "Breathing"
+	}
+
+	return fmt.Sprintf("Unknown(0x%02x)", byte(mode))
+}
+
+// String returns the color as RGBWAUV channel values.
+func (color Color) String() string {
+	return fmt.Sprintf("RGBWAUV(%d, %d, %d, %d, %d, %d)", color.Red, color.Green, color.Blue, color.White, color.Amber, color.UltraViolet)
+}
+
 func CreateDisplayStatusUpdate(statusCode DisplayStatusCode, serverId byte) MessageBlock {
 	message := BusMessage{StatusCode, []byte{byte(statusCode), serverId}}
 	return []BusMessage{message}
@@ -124,6 +177,59 @@ func CreateLightingStrobe(color Color, frequency uint16, rolloff byte, applyOnBe
 	return []BusMessage{colorMessage, speedMessage, gpMessage, modeMessage, applyMessage}
 }
 
+// CreateLightingChase lights up Boxi1 and Boxi2 alternately on every beat, advancing through the palette.
+// The unlit Boxi is dimmed to the background brightness.
+func CreateLightingChase(palette []Color, backgroundBrightness byte, applyOnBeat bool) (MessageBlock, error) {
+	paletteMessages, err := convertPalette(palette)
+	if err != nil {
+		return nil, err
+	}
+
+	gpMessage := BusMessage{LightingGeneralPurpose, []byte{backgroundBrightness}}
+	modeMessage := BusMessage{LightingMode, []byte{byte(Chase)}}
+	applyMessage := BusMessage{LightingApply, convertBool(applyOnBeat)}
+	return append(paletteMessages, gpMessage, modeMessage, applyMessage), nil
+}
+
+// CreateLightingRainbowCycle continuously cycles through the HSV hues. The speed is the number of cycles for one
+// rotation, the hue offset of Boxi2 is given in 1/256 of a rotation.
+func CreateLightingRainbowCycle(speed uint16, hueOffset byte, saturation byte, applyOnBeat bool) MessageBlock {
+	speedMessage := BusMessage{LightingSpeed, convertShort(speed)}
+	shiftMessage := BusMessage{LightingColorShift, []byte{hueOffset}}
+	gpMessage := BusMessage{LightingGeneralPurpose, []byte{saturation}}
+	modeMessage := BusMessage{LightingMode, []byte{byte(RainbowCycle)}}
+	applyMessage := BusMessage{LightingApply, convertBool(applyOnBeat)}
+	return []BusMessage{speedMessage, shiftMessage, gpMessage, modeMessage, applyMessage}
+}
+
+// CreateLightingBreathing fades the brightness of the palette colors along a sine wave with a period of the given
+// number of cycles, switching to the next color at the darkest point.
+func CreateLightingBreathing(palette []Color, speed uint16, minBrightness byte, paletteShift byte, applyOnBeat bool) (MessageBlock, error) {
+	paletteMessages, err := convertPalette(palette)
+	if err != nil {
+		return nil, err
+	}
+
+	speedMessage := BusMessage{LightingSpeed, convertShort(speed)}
+	gpMessage := BusMessage{LightingGeneralPurpose, []byte{minBrightness}}
+	shiftMessage := BusMessage{LightingColorShift, []byte{paletteShift}}
+	modeMessage := BusMessage{LightingMode, []byte{byte(Breathing)}}
+	applyMessage := BusMessage{LightingApply, convertBool(applyOnBeat)}
+	return append(paletteMessages, speedMessage, gpMessage, shiftMessage, modeMessage, applyMessage), nil
+}
+
+// CreateLightingForBoxi limits a lighting block to the given Boxis. The target is reset to both Boxis once the
+// lighting is applied.
+func CreateLightingForBoxi(target LightingTarget, lighting MessageBlock) MessageBlock {
+	targetMessage := BusMessage{LightingSelectTarget, []byte{byte(target)}}
+	return append(MessageBlock{targetMessage}, lighting...)
+}
+
+// CreateLightingPerBoxi combines separate lighting blocks for each Boxi into a single block.
+func CreateLightingPerBoxi(boxi1 MessageBlock, boxi2 MessageBlock) MessageBlock {
+	return append(CreateLightingForBoxi(TargetBoxi1, boxi1), CreateLightingForBoxi(TargetBoxi2, boxi2)...)
+}
+
 func CreateConfigInternalLeds(enableLeds bool) MessageBlock {
 	var bitSetVar byte
 	if enableLeds {
@@ -134,6 +240,17 @@ func CreateConfigInternalLeds(enableLeds bool) MessageBlock {
 	return []BusMessage{message}
 }
 
+// GetLightingMode returns the lighting mode set by the block, if any.
+func (block MessageBlock) GetLightingMode() (LightingModeId, bool) {
+	for _, message := range block {
+		if message.field == LightingMode {
+			return LightingModeId(message.payload[0]), true
+		}
+	}
+
+	return Off, false
+}
+
 func convertShort(short uint16) []byte {
 	return []byte{byte(short >> 8), byte(short & 0xff)}
 }
@@ -147,20 +264,109 @@ func convertBool(input bool) []byte {
 
 }
 
+// convertPalette writes the palette into the palette fields. Palettes longer than the palette fields are written
+// in pages, each preceded by the offset of its first color. The Arduino resets the offset with every LightingApply.
 func convertPalette(palette []Color) ([]BusMessage, error) {
 	paletteLen := len(palette)
-	if paletteLen > 8 {
-		return nil, errors.New("palette length cannot exceed 8")
+	if paletteLen > MaxPaletteSize {
+		return nil, fmt.Errorf("palette length cannot exceed %d", MaxPaletteSize)
 	}
 
-	colorMessages := make([]BusMessage, paletteLen+1)
-	colorMessages[0] = BusMessage{LightingPaletteSize, []byte{byte(paletteLen)}}
+	colorMessages := []BusMessage{{LightingPaletteSize, []byte{byte(paletteLen)}}}
 	for i := 0; i < paletteLen; i++ {
-		colorMessages[i+1] = BusMessage{LightingPaletteA + MemoryField(i), convertColor(palette[i])}
+		if i%palettePageSize == 0 && paletteLen > palettePageSize {
+			colorMessages = append(colorMessages, BusMessage{LightingPaletteOffset, []byte{byte(i)}})
+		}
+
+		field := LightingPaletteA + MemoryField(i%palettePageSize)
+		colorMessages = append(colorMessages, BusMessage{field, convertColor(palette[i])})
 	}
 	return colorMessages, nil
 }
 
+// withoutBoxiTargets converts the block for firmware that runs the same lighting on both Boxis.
+// Lighting targeting Boxi1 is applied to both Boxis, lighting targeting only Boxi2 is dropped.
+func (block MessageBlock) withoutBoxiTargets() MessageBlock {
+	var result MessageBlock
+	target := TargetBothBoxis
+
+	for _, message := range block {
+		if message.field == LightingSelectTarget {
+			target = LightingTarget(message.payload[0])
+			continue
+		}
+
+		if target&TargetBoxi1 != 0 || !isLightingField(message.field) {
+			result = append(result, message)
+		}
+
+		if message.field == LightingApply {
+			target = TargetBothBoxis
+		}
+	}
+
+	return result
+}
+
+// lightingPrograms returns the last lighting program the block applies to each Boxi, without target selection.
+func (block MessageBlock) lightingPrograms() (boxi1 MessageBlock, boxi2 MessageBlock) {
+	var current MessageBlock
+	target := TargetBothBoxis
+
+	for _, message := range block {
+		switch {
+		case message.field == LightingSelectTarget:
+			target = LightingTarget(message.payload[0])
+		case isLightingField(message.field):
+			current = append(current, message)
+		}
+
+		if message.field != LightingApply {
+			continue
+		}
+
+		if target&TargetBoxi1 != 0 {
+			boxi1 = current
+		}
+		if target&TargetBoxi2 != 0 {
+			boxi2 = current
+		}
+		current = nil
+		target = TargetBothBoxis
+	}
+
+	return boxi1, boxi2
+}
+
+// isLightingField returns whether the field is part of a lighting program.
+func isLightingField(field MemoryField) bool {
+	return field >= LightingApply && field <= LightingPaletteH || field == LightingPaletteOffset
+}
+
+// withoutPalettePages drops all palette pages but the first, for firmware that only knows the palette fields.
+func (block MessageBlock) withoutPalettePages() MessageBlock {
+	var result MessageBlock
+	var offset byte
+
+	for _, message := range block {
+		switch {
+		case message.field == LightingPaletteOffset:
+			offset = message.payload[0]
+			continue
+		case message.field == LightingPaletteSize:
+			message = BusMessage{LightingPaletteSize, []byte{min(message.payload[0], palettePageSize)}}
+		case message.field >= LightingPaletteA && message.field <= LightingPaletteH && offset > 0:
+			continue
+		case message.field == LightingApply:
+			offset = 0
+		}
+
+		result = append(result, message)
+	}
+
+	return result
+}
+
 func convertColor(color Color) []byte {
 	return []byte{
 		color.Red,
@@ -171,3 +377,12 @@ func convertColor(color Color) []byte {
 		color.UltraViolet,
 	}
 }
+
+// index returns the index of a single Boxi.
+func (target LightingTarget) index() int {
+	if target == TargetBoxi2 {
+		return 1
+	}
+
+	return 0
+}
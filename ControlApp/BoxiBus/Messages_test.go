@@ -0,0 +1,103 @@
+package BoxiBus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func createTestPalette(size int) []Color {
+	palette := make([]Color, size)
+	for i := range palette {
+		palette[i] = Color{Red: byte(i + 1)}
+	}
+
+	return palette
+}
+
+func mustCreatePaletteSwitch(t *testing.T, palette []Color) MessageBlock {
+	block, err := CreateLightingPaletteSwitch(palette, 0, false)
+	if err != nil {
+		t.Fatalf("creating the block failed: %s", err)
+	}
+
+	return block
+}
+
+func TestWithoutPalettePages(t *testing.T) {
+	short := mustCreatePaletteSwitch(t, createTestPalette(palettePageSize))
+	firstPage := mustCreatePaletteSwitch(t, createTestPalette(palettePageSize))
+	paged := mustCreatePaletteSwitch(t, createTestPalette(12))
+
+	tests := []struct {
+		name     string
+		block    MessageBlock
+		expected MessageBlock
+	}{
+		{
+			name:     "single page",
+			block:    short,
+			expected: short,
+		},
+		{
+			name:     "pages",
+			block:    paged,
+			expected: firstPage,
+		},
+		{
+			//The offset is reset by every apply, so the next program starts with its first page again
+			name:     "pages of every program",
+			block:    CreateLightingPerBoxi(paged, paged),
+			expected: CreateLightingPerBoxi(firstPage, firstPage),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := test.block.withoutPalettePages(); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("converted to %v, expected %v", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestWithoutBoxiTargets(t *testing.T) {
+	boxi1 := CreateLightingSetColor(Color{Red: 255}, Color{Green: 255}, false)
+	boxi2 := CreateLightingSetColor(Color{Blue: 255}, Color{Green: 255}, false)
+	config := CreateConfigInternalLeds(true)
+
+	tests := []struct {
+		name     string
+		block    MessageBlock
+		expected MessageBlock
+	}{
+		{
+			name:     "no target",
+			block:    boxi1,
+			expected: boxi1,
+		},
+		{
+			name:     "program of each Boxi",
+			block:    CreateLightingPerBoxi(boxi1, boxi2),
+			expected: boxi1,
+		},
+		{
+			//The program of Boxi 2 would replace the one of Boxi 1 on firmware without targets
+			name:     "program of Boxi 2 only",
+			block:    append(CreateLightingForBoxi(TargetBoxi2, boxi2), config...),
+			expected: config,
+		},
+		{
+			name:     "target reset by the apply",
+			block:    append(CreateLightingForBoxi(TargetBoxi2, boxi2), boxi1...),
+			expected: boxi1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := test.block.withoutBoxiTargets(); !test.expected.equals(result) {
+				t.Errorf("converted to %v, expected %v", result, test.expected)
+			}
+		})
+	}
+}
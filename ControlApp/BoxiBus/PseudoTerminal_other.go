@@ -0,0 +1,12 @@
+//go:build !linux
+
+package BoxiBus
+
+import (
+	"errors"
+	"os"
+)
+
+func openPseudoTerminal() (*os.File, *os.File, error) {
+	return nil, nil, errors.New("the Arduino simulator is only supported on Linux")
+}
@@ -0,0 +1,198 @@
+package BoxiBus
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"sync"
+)
+
+// Simulator emulates the Arduino on the other end of a pseudo-terminal, so the
+// whole lighting path can be exercised without the actual hardware.
+type Simulator struct {
+	DevicePath      string
+	protocolVersion byte
+	bitErrorRate    float64
+	memory          *ArduinoMemory
+	master          *os.File
+	slave           *os.File
+	lock            *sync.Mutex
+	onMessage       func(message BusMessage, applied LightingTarget, memory ArduinoMemory)
+}
+
+// corruptingReader flips random bits of the received data to emulate a noisy UART.
+type corruptingReader struct {
+	simulator *Simulator
+}
+
+// StartSimulator opens a pseudo-terminal and starts parsing the bus messages written to it.
+// The returned DevicePath can be passed to ConnectToArduino in place of the UART. The protocol version
+// limits the framing the simulated firmware understands, 1 emulates firmware predating the negotiation like
+// lightshow_v3, which neither acknowledges frames nor sends telemetry.
+func StartSimulator(protocolVersion byte, onMessage func(message BusMessage, applied LightingTarget, memory ArduinoMemory)) (*Simulator, error) {
+	master, slave, err := openPseudoTerminal()
+	if err != nil {
+		return nil, err
+	}
+
+	simulator := &Simulator{
+		DevicePath:      slave.Name(),
+		protocolVersion: protocolVersion,
+		memory:          CreateArduinoMemory(),
+		master:          master,
+		slave:           slave,
+		lock:            &sync.Mutex{},
+		onMessage:       onMessage,
+	}
+
+	go simulator.receive()
+	return simulator, nil
+}
+
+// GetMemory returns a snapshot of the simulated Arduino memory.
+func (simulator *Simulator) GetMemory() ArduinoMemory {
+	simulator.lock.Lock()
+	defer simulator.lock.Unlock()
+
+	return *simulator.memory
+}
+
+// Beat simulates an impulse on the beat input of the Arduino. It returns the Boxis whose lighting was applied.
+func (simulator *Simulator) Beat() LightingTarget {
+	simulator.lock.Lock()
+	defer simulator.lock.Unlock()
+
+	simulator.report(BusMessage{BeatPulse, []byte{}})
+	applied := simulator.memory.Beat()
+	simulator.reportApplied(applied, *simulator.memory)
+	return applied
+}
+
+// SetBrightness simulates turning the brightness potentiometer of the Arduino.
+func (simulator *Simulator) SetBrightness(brightness float64) {
+	simulator.lock.Lock()
+	defer simulator.lock.Unlock()
+
+	simulator.report(BusMessage{BrightnessReport, convertShort(uint16(brightness * 1000))})
+}
+
+// SetBitErrorRate sets the probability of every received bit being flipped.
+func (simulator *Simulator) SetBitErrorRate(rate float64) {
+	simulator.lock.Lock()
+	defer simulator.lock.Unlock()
+
+	simulator.bitErrorRate = rate
+}
+
+func (simulator *Simulator) Close() error {
+	_ = simulator.slave.Close()
+	return simulator.master.Close()
+}
+
+// reportApplied reports the modes that took effect on the Boxis, starting with Boxi1.
+func (simulator *Simulator) reportApplied(applied LightingTarget, memory ArduinoMemory) {
+	if applied&TargetBoxi1 != 0 {
+		simulator.report(BusMessage{LightingApplied, []byte{byte(memory.Active(TargetBoxi1).Mode)}})
+	}
+
+	if applied&TargetBoxi2 != 0 && (applied&TargetBoxi1 == 0 || memory.Active(TargetBoxi1).Mode != memory.Active(TargetBoxi2).Mode) {
+		simulator.report(BusMessage{LightingApplied, []byte{byte(memory.Active(TargetBoxi2).Mode)}})
+	}
+}
+
+// report sends a telemetry message back to the host, just like firmware speaking protocol v2 or newer does.
+func (simulator *Simulator) report(message BusMessage) {
+	if simulator.protocolVersion < telemetryProtocolVersion {
+		return
+	}
+
+	frame, err := encodeTelemetry(message)
+	if err != nil {
+		return
+	}
+
+	_, _ = simulator.master.Write(frame)
+}
+
+func (simulator *Simulator) receive() {
+	reader := newFrameReader(corruptingReader{simulator})
+	var lastSequence *byte
+
+	for {
+		received, err := reader.readFrame()
+		if received.version > simulator.protocolVersion {
+			continue
+		}
+
+		//Older firmware ignores the fields introduced after it
+		isTransactionField := received.field == TransactionBegin || received.field == TransactionCommit
+		if isTransactionField && simulator.protocolVersion < transactionProtocolVersion {
+			continue
+		}
+		if received.field == LightingPaletteOffset && simulator.protocolVersion < palettePagingVersion {
+			continue
+		}
+		if received.field == LightingSelectTarget && simulator.protocolVersion < perBoxiLightingVersion {
+			continue
+		}
+
+		if errors.Is(err, errInvalidChecksum) {
+			simulator.lock.Lock()
+			simulator.report(BusMessage{FrameRejected, []byte{received.sequence}})
+			simulator.lock.Unlock()
+			continue
+		} else if err != nil {
+			return
+		}
+
+		message := received.BusMessage
+		simulator.lock.Lock()
+
+		//Retransmitted frames are acknowledged again, but only applied once
+		if received.version >= 2 {
+			simulator.report(BusMessage{FrameAcknowledge, []byte{received.sequence}})
+			if lastSequence != nil && *lastSequence == received.sequence {
+				simulator.lock.Unlock()
+				continue
+			}
+			lastSequence = &received.sequence
+		}
+
+		applied, _ := simulator.memory.Write(message)
+		snapshot := *simulator.memory
+		simulator.reportApplied(applied, snapshot)
+		if message.field == StatusCode {
+			simulator.report(BusMessage{StatusReport, []byte{byte(snapshot.StatusCode), snapshot.StatusServerId}})
+		}
+		if message.field == ProtocolVersion && simulator.protocolVersion >= framingProtocolVersion {
+			simulator.report(BusMessage{ProtocolVersionReport, []byte{simulator.protocolVersion}})
+		}
+		simulator.lock.Unlock()
+
+		if simulator.onMessage != nil {
+			simulator.onMessage(message, applied, snapshot)
+		}
+	}
+}
+
+func (reader corruptingReader) Read(buffer []byte) (int, error) {
+	n, err := reader.simulator.master.Read(buffer)
+
+	reader.simulator.lock.Lock()
+	rate := reader.simulator.bitErrorRate
+	reader.simulator.lock.Unlock()
+
+	if rate <= 0 {
+		return n, err
+	}
+
+	for i := 0; i < n; i++ {
+		for bit := 0; bit < 8; bit++ {
+			if rand.Float64() < rate {
+				buffer[i] ^= 1 << bit
+			}
+		}
+	}
+
+	return n, err
+}
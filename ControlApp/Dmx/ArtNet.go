@@ -0,0 +1,52 @@
+package Dmx
+
+import "fmt"
+
+const (
+	artNetPort               = 6454
+	artNetOpDmx       uint16 = 0x5000
+	artNetVersion     uint16 = 14
+	maxArtNetUniverse        = 0x7FFF
+)
+
+var artNetId = []byte("Art-Net\x00")
+
+// encodeArtDmx creates an ArtDmx packet carrying the whole universe.
+func encodeArtDmx(universeId uint16, sequence byte, universe *Universe) []byte {
+	packet := make([]byte, 0, 18+UniverseSize)
+	packet = append(packet, artNetId...)
+	packet = append(packet, byte(artNetOpDmx&0xFF), byte(artNetOpDmx>>8))     //Little endian
+	packet = append(packet, byte(artNetVersion>>8), byte(artNetVersion&0xFF)) //Big endian
+	packet = append(packet, sequence, 0)
+	packet = append(packet, byte(universeId), byte(universeId>>8)) //SubUni and Net
+	packet = append(packet, byte(UniverseSize>>8), byte(UniverseSize&0xFF))
+	return append(packet, universe[:]...)
+}
+
+func validateArtNetUniverse(universeId uint16) error {
+	if universeId > maxArtNetUniverse {
+		return fmt.Errorf("Art-Net universe %d is above %d", universeId, maxArtNetUniverse)
+	}
+
+	return nil
+}
+
+// decodeArtDmx returns the universe and channel data of an ArtDmx packet.
+func decodeArtDmx(packet []byte) (uint16, []byte, bool) {
+	if len(packet) < 18 || string(packet[:8]) != string(artNetId) {
+		return 0, nil, false
+	}
+
+	opCode := uint16(packet[8]) | uint16(packet[9])<<8
+	if opCode != artNetOpDmx {
+		return 0, nil, false
+	}
+
+	universeId := uint16(packet[14]) | uint16(packet[15]&0x7F)<<8
+	length := int(packet[16])<<8 | int(packet[17])
+	if length > UniverseSize || len(packet) < 18+length {
+		return 0, nil, false
+	}
+
+	return universeId, packet[18 : 18+length], true
+}
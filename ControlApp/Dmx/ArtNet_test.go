@@ -0,0 +1,74 @@
+package Dmx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeArtDmx(t *testing.T) {
+	universe := createTestUniverse()
+	packet := encodeArtDmx(0x1234, 42, universe)
+
+	fields := []struct {
+		name     string
+		offset   int
+		expected []byte
+	}{
+		{"ID", 0, []byte("Art-Net\x00")},
+		{"OpCode", 8, []byte{0x00, 0x50}},
+		{"protocol version", 10, []byte{0x00, 14}},
+		{"sequence", 12, []byte{42}},
+		{"physical", 13, []byte{0}},
+		{"SubUni and Net", 14, []byte{0x34, 0x12}},
+		{"length", 16, []byte{0x02, 0x00}},
+		{"data", 18, universe[:]},
+	}
+
+	if len(packet) != 18+UniverseSize {
+		t.Fatalf("packet has %d bytes, expected %d", len(packet), 18+UniverseSize)
+	}
+
+	for _, field := range fields {
+		if actual := packet[field.offset : field.offset+len(field.expected)]; !bytes.Equal(actual, field.expected) {
+			t.Errorf("%s at offset %d is % x, expected % x", field.name, field.offset, actual, field.expected)
+		}
+	}
+}
+
+func TestDecodeArtDmx(t *testing.T) {
+	universe := createTestUniverse()
+	packet := encodeArtDmx(0x1234, 42, universe)
+
+	universeId, data, ok := decodeArtDmx(packet)
+	if !ok || universeId != 0x1234 || !bytes.Equal(data, universe[:]) {
+		t.Errorf("decoding returned universe %d and %d channels, ok: %t", universeId, len(data), ok)
+	}
+
+	poll := append([]byte{}, packet...)
+	poll[8], poll[9] = 0x00, 0x20 //OpPoll
+
+	short := append([]byte{}, packet[:18+100]...)
+	short[16], short[17] = 0x00, 100
+
+	tests := []struct {
+		name     string
+		packet   []byte
+		ok       bool
+		channels int
+	}{
+		{"fewer channels", short, true, 100},
+		{"other OpCode", poll, false, 0},
+		{"other protocol", append([]byte("Art-Nes\x00"), packet[8:]...), false, 0},
+		{"truncated header", packet[:17], false, 0},
+		{"truncated data", packet[:100], false, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, data, ok := decodeArtDmx(test.packet)
+			if ok != test.ok || len(data) != test.channels {
+				t.Errorf("decoding returned %d channels, ok: %t, expected %d channels, ok: %t", len(data), ok, test.channels, test.ok)
+			}
+		})
+	}
+}
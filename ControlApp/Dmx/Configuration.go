@@ -0,0 +1,84 @@
+package Dmx
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+)
+
+// FixtureConfiguration describes how the universes are transmitted and which fixtures are patched into them.
+type FixtureConfiguration struct {
+	Outputs  []OutputConfiguration
+	Fixtures []Fixture
+	Input    *InputConfiguration //The lighting desk that may take over the lighting, nil if there is none
+}
+
+// Protocol is the way a universe is transmitted.
+type Protocol string
+
+const (
+	UsbWidget Protocol = "enttec" //Enttec DMX USB Pro compatible widget
+	ArtNet    Protocol = "artnet" //Art-Net over UDP
+	Sacn      Protocol = "sacn"   //Streaming ACN (E1.31) over UDP
+)
+
+// OutputConfiguration transmits a single universe.
+type OutputConfiguration struct {
+	Protocol    Protocol
+	Universe    uint16
+	Device      string //The serial device of the USB widget
+	Destination string //The host of the network node, broadcast or multicast if empty
+	Priority    byte   //The sACN priority, 100 if not set
+}
+
+// InputConfiguration describes the universe a lighting desk controls the Boxis with.
+type InputConfiguration struct {
+	Protocol   Protocol //Either Art-Net or sACN
+	Universe   uint16
+	Address    uint16 //First channel of the desk controls, starting at 1
+	TimeoutSec uint16 //How long the desk may be quiet before auto mode takes over again, 5 s if not set
+}
+
+const fixtureConfigPath = "Configuration/fixtures.json"
+
+// LoadFixtureConfiguration reads the fixture patching. Without it, no fixtures are driven.
+func LoadFixtureConfiguration() FixtureConfiguration {
+	var config FixtureConfiguration
+
+	configFile, err := os.Open(fixtureConfigPath)
+	if err != nil {
+		log.Printf("Config file for fixtures could not be accessed, no fixtures are driven. %s", err)
+		return config
+	}
+
+	defer func(configFile *os.File) {
+		_ = configFile.Close()
+	}(configFile)
+
+	if err := json.NewDecoder(configFile).Decode(&config); err != nil {
+		log.Fatalf("Invalid JSON format of fixture config file! %s", err)
+	}
+
+	if err := config.validateInput(); err != nil {
+		log.Fatalf("Invalid fixture config file! %s", err)
+	}
+
+	return config
+}
+
+// validateInput makes sure the lighting desk input doesn't receive the output. Art-Net is broadcast and sACN
+// multicast loops back, so the output would be taken for the desk and never hand back control.
+func (config FixtureConfiguration) validateInput() error {
+	if config.Input == nil {
+		return nil
+	}
+
+	for _, output := range config.Outputs {
+		if output.Protocol == config.Input.Protocol && output.Universe == config.Input.Universe {
+			return fmt.Errorf("the lighting desk input uses %s universe %d, which is also an output", output.Protocol, output.Universe)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package Dmx
+
+import "testing"
+
+func TestValidateInput(t *testing.T) {
+	outputs := []OutputConfiguration{
+		{Protocol: ArtNet, Universe: 1},
+		{Protocol: Sacn, Universe: 2},
+		{Protocol: UsbWidget, Universe: 3, Device: "/dev/ttyUSB0"},
+	}
+
+	tests := []struct {
+		name  string
+		input *InputConfiguration
+		valid bool
+	}{
+		{"no input", nil, true},
+		{"separate Art-Net universe", &InputConfiguration{Protocol: ArtNet, Universe: 2}, true},
+		{"separate sACN universe", &InputConfiguration{Protocol: Sacn, Universe: 1}, true},
+		{"universe of the USB widget", &InputConfiguration{Protocol: ArtNet, Universe: 3}, true},
+		{"Art-Net output universe", &InputConfiguration{Protocol: ArtNet, Universe: 1}, false},
+		{"sACN output universe", &InputConfiguration{Protocol: Sacn, Universe: 2}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := FixtureConfiguration{Outputs: outputs, Input: test.input}.validateInput()
+			if (err == nil) != test.valid {
+				t.Errorf("validation returned %v, expected valid: %t", err, test.valid)
+			}
+		})
+	}
+}
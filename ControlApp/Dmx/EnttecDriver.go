@@ -0,0 +1,104 @@
+package Dmx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"go.bug.st/serial"
+)
+
+// Framing of the Enttec DMX USB Pro widget API
+const (
+	enttecStartDelimiter byte = 0x7E
+	enttecEndDelimiter   byte = 0xE7
+	enttecSendDmxLabel   byte = 0x06
+	dmxStartCode         byte = 0x00
+)
+
+// EnttecDriver outputs universes through an Enttec DMX USB Pro compatible widget.
+// The widget keeps repeating the last universe it received on the DMX line.
+type EnttecDriver struct {
+	port io.WriteCloser
+}
+
+// OpenEnttecDriver opens the serial port of the widget at the given device path.
+func OpenEnttecDriver(device string) (*EnttecDriver, error) {
+	//The widget is a USB device, the baud rate is ignored
+	mode := &serial.Mode{
+		BaudRate: 57600,
+		DataBits: 8,
+		Parity:   serial.NoParity,
+		StopBits: serial.OneStopBit,
+	}
+
+	port, err := serial.Open(device, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DMX widget %s: %w", device, err)
+	}
+
+	return CreateEnttecDriver(port), nil
+}
+
+// CreateEnttecDriver uses an already opened connection to the widget.
+func CreateEnttecDriver(port io.WriteCloser) *EnttecDriver {
+	return &EnttecDriver{port: port}
+}
+
+// Send transmits the universe to the widget.
+func (driver *EnttecDriver) Send(universe *Universe) error {
+	_, err := driver.port.Write(EncodeEnttecPacket(universe))
+	return err
+}
+
+func (driver *EnttecDriver) Close() error {
+	return driver.port.Close()
+}
+
+// EncodeEnttecPacket frames the universe as an "Output Only Send DMX Packet" request.
+func EncodeEnttecPacket(universe *Universe) []byte {
+	length := UniverseSize + 1
+
+	packet := make([]byte, 0, length+5)
+	packet = append(packet, enttecStartDelimiter, enttecSendDmxLabel, byte(length), byte(length>>8), dmxStartCode)
+	packet = append(packet, universe[:]...)
+	return append(packet, enttecEndDelimiter)
+}
+
+// ReadEnttecPacket reads the next DMX packet request from the reader, skipping anything that isn't one.
+// Shorter universes are padded with zeros. It allows checking the output against a serial loopback.
+func ReadEnttecPacket(reader io.Reader) (Universe, error) {
+	var universe Universe
+	single := make([]byte, 1)
+
+	for {
+		if _, err := io.ReadFull(reader, single); err != nil {
+			return universe, err
+		}
+		if single[0] != enttecStartDelimiter {
+			continue
+		}
+
+		header := make([]byte, 3)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return universe, err
+		}
+
+		length := int(header[1]) | int(header[2])<<8
+		data := make([]byte, length+1)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return universe, err
+		}
+
+		if data[length] != enttecEndDelimiter {
+			return universe, errors.New("DMX widget packet is missing its end delimiter")
+		}
+
+		if header[0] != enttecSendDmxLabel || length == 0 || data[0] != dmxStartCode {
+			continue
+		}
+
+		copy(universe[:], data[1:length])
+		return universe, nil
+	}
+}
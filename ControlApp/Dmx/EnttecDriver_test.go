@@ -0,0 +1,105 @@
+package Dmx
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// pipeStream writes the chunks into a pipe and closes it, like a serial loopback that gets unplugged afterwards.
+func pipeStream(chunks ...[]byte) io.Reader {
+	reader, writer := io.Pipe()
+	go func() {
+		for _, chunk := range chunks {
+			if _, err := writer.Write(chunk); err != nil {
+				return
+			}
+		}
+		_ = writer.Close()
+	}()
+
+	return reader
+}
+
+func createTestUniverse() *Universe {
+	universe := &Universe{}
+	for i := range universe {
+		universe[i] = byte(i * 7)
+	}
+
+	return universe
+}
+
+func TestEnttecDriverRoundTrip(t *testing.T) {
+	universe := createTestUniverse()
+	reader, writer := io.Pipe()
+	driver := CreateEnttecDriver(writer)
+
+	go func() {
+		_ = driver.Send(universe)
+		_ = driver.Close()
+	}()
+
+	received, err := ReadEnttecPacket(reader)
+	if err != nil {
+		t.Fatalf("reading the packet failed: %s", err)
+	}
+
+	if received != *universe {
+		t.Error("the received universe differs from the sent one")
+	}
+}
+
+func TestReadEnttecPacket(t *testing.T) {
+	universe := createTestUniverse()
+	packet := EncodeEnttecPacket(universe)
+
+	badEnd := append([]byte{}, packet...)
+	badEnd[len(badEnd)-1] = 0x00
+
+	//A widget reply with another label, which is skipped
+	otherLabel := []byte{enttecStartDelimiter, 0x0A, 0x04, 0x00, 0x01, 0x02, 0x03, 0x04, enttecEndDelimiter}
+
+	//Only the first channels, the rest is padded with zeros
+	short := []byte{enttecStartDelimiter, enttecSendDmxLabel, 0x04, 0x00, dmxStartCode, 0x10, 0x20, 0x30, enttecEndDelimiter}
+	shortUniverse := Universe{0x10, 0x20, 0x30}
+
+	tests := []struct {
+		name     string
+		stream   [][]byte
+		expected Universe
+		err      error //The error expected if failing, nil accepts any
+		failing  bool
+	}{
+		{name: "complete packet", stream: [][]byte{packet}, expected: *universe},
+		{name: "leading noise", stream: [][]byte{{0x00, 0xE7, 0x42}, packet}, expected: *universe},
+		{name: "other label", stream: [][]byte{otherLabel, packet}, expected: *universe},
+		{name: "short universe", stream: [][]byte{short}, expected: shortUniverse},
+		{name: "split over writes", stream: [][]byte{packet[:3], packet[3:100], packet[100:]}, expected: *universe},
+		{name: "truncated frame", stream: [][]byte{packet[:len(packet)/2]}, err: io.ErrUnexpectedEOF, failing: true},
+		{name: "bad end byte", stream: [][]byte{badEnd}, failing: true},
+		{name: "nothing received", stream: nil, err: io.EOF, failing: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			received, err := ReadEnttecPacket(pipeStream(test.stream...))
+			if test.failing {
+				if err == nil {
+					t.Fatal("reading succeeded, expected an error")
+				}
+				if test.err != nil && !errors.Is(err, test.err) {
+					t.Fatalf("reading failed with %q, expected %q", err, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("reading the packet failed: %s", err)
+			}
+			if received != test.expected {
+				t.Error("the received universe differs from the sent one")
+			}
+		})
+	}
+}
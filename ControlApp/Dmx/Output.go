@@ -0,0 +1,94 @@
+package Dmx
+
+import (
+	"ControlApp/BoxiBus"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// How often the universes are resent, network receivers consider a source lost after 2.5 s
+const refreshInterval = time.Second
+
+// Output drives DMX fixtures with the colors rendered for the Boxis.
+type Output struct {
+	lock         *sync.Mutex
+	transmitters []Transmitter
+	failing      []bool //Whether the last transmission of a transmitter failed
+	fixtures     []Fixture
+	universes    map[uint16]*Universe
+}
+
+// StartOutput validates the fixture patching and starts transmitting the universes.
+// Outputs that fail, like an unplugged USB widget, are retried in the background.
+func StartOutput(config FixtureConfiguration) (*Output, error) {
+	output := &Output{
+		lock:      &sync.Mutex{},
+		fixtures:  config.Fixtures,
+		universes: make(map[uint16]*Universe),
+	}
+
+	for _, outputConfig := range config.Outputs {
+		transmitter, err := CreateTransmitter(outputConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s output: %w", outputConfig.Protocol, err)
+		}
+
+		output.transmitters = append(output.transmitters, transmitter)
+		output.failing = append(output.failing, false)
+		output.universes[transmitter.Universe()] = &Universe{}
+	}
+
+	for _, fixture := range config.Fixtures {
+		if err := fixture.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid DMX fixture %s: %w", fixture.Name, err)
+		}
+
+		if _, ok := output.universes[fixture.Universe]; !ok {
+			return nil, fmt.Errorf("DMX fixture %s is patched into universe %d, which has no output", fixture.Name, fixture.Universe)
+		}
+	}
+
+	output.SetColors(BoxiBus.Color{}, BoxiBus.Color{})
+	go output.refresh()
+	return output, nil
+}
+
+// SetColors shows the colors of the Boxis on the fixtures following them.
+func (output *Output) SetColors(boxi1 BoxiBus.Color, boxi2 BoxiBus.Color) {
+	output.lock.Lock()
+	defer output.lock.Unlock()
+
+	for _, fixture := range output.fixtures {
+		color := boxi1
+		if fixture.Boxi == BoxiBus.TargetBoxi2 {
+			color = boxi2
+		}
+
+		output.universes[fixture.Universe].SetFixtureColor(fixture, color)
+	}
+
+	output.send()
+}
+
+// refresh keeps resending the universes while no new colors are set.
+func (output *Output) refresh() {
+	for range time.Tick(refreshInterval) {
+		output.lock.Lock()
+		output.send()
+		output.lock.Unlock()
+	}
+}
+
+// send transmits all universes. Failures are only logged once until the transmitter recovers. Requires the lock.
+func (output *Output) send() {
+	for i, transmitter := range output.transmitters {
+		err := transmitter.Transmit(output.universes[transmitter.Universe()])
+		if err != nil && !output.failing[i] {
+			log.Printf("Transmitting DMX universe %d failed, retrying: %s", transmitter.Universe(), err)
+		}
+
+		output.failing[i] = err != nil
+	}
+}
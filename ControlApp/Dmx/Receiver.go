@@ -0,0 +1,68 @@
+package Dmx
+
+import (
+	"fmt"
+	"log"
+	"net"
+)
+
+// ListenForInput receives the configured universe over the network. Every received packet of the
+// universe is reported on the returned channel; packets that arrive while the last one is still
+// being processed are dropped.
+func ListenForInput(config InputConfiguration) (<-chan Universe, error) {
+	var connection *net.UDPConn
+	var decode func(packet []byte) (uint16, []byte, bool)
+	var err error
+
+	switch config.Protocol {
+	case ArtNet:
+		if err := validateArtNetUniverse(config.Universe); err != nil {
+			return nil, err
+		}
+
+		connection, err = net.ListenUDP("udp4", &net.UDPAddr{Port: artNetPort})
+		decode = decodeArtDmx
+	case Sacn:
+		if err := validateSacnUniverse(config.Universe); err != nil {
+			return nil, err
+		}
+
+		group := &net.UDPAddr{IP: net.ParseIP(getSacnMulticastAddress(config.Universe)), Port: sacnPort}
+		connection, err = net.ListenMulticastUDP("udp4", nil, group)
+		decode = decodeE131
+	default:
+		return nil, fmt.Errorf("unknown input protocol %q", config.Protocol)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for %s input: %w", config.Protocol, err)
+	}
+
+	universes := make(chan Universe, 1)
+	go receive(connection, config.Universe, decode, universes)
+	return universes, nil
+}
+
+func receive(connection *net.UDPConn, universeId uint16, decode func(packet []byte) (uint16, []byte, bool), universes chan<- Universe) {
+	buffer := make([]byte, 1024)
+
+	for {
+		length, err := connection.Read(buffer)
+		if err != nil {
+			log.Printf("Receiving DMX input failed, the input stops: %s", err)
+			return
+		}
+
+		receivedId, data, ok := decode(buffer[:length])
+		if !ok || receivedId != universeId {
+			continue
+		}
+
+		var universe Universe
+		copy(universe[:], data)
+		select {
+		case universes <- universe:
+		default:
+		}
+	}
+}
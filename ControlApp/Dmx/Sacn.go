@@ -0,0 +1,108 @@
+package Dmx
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+const (
+	sacnPort                 = 5568
+	sacnDefaultPriority      = 100
+	minSacnUniverse          = 1
+	maxSacnUniverse          = 63999
+	sacnSourceName           = "ControlApp"
+	sacnPacketSize           = 638
+	sacnRootVector           = 0x00000004
+	sacnFramingVector        = 0x00000002
+	sacnDmpVector       byte = 0x02
+)
+
+var sacnPacketId = []byte("ASC-E1.17\x00\x00\x00")
+
+// sacnComponentId identifies this source to the receivers for as long as the application runs.
+var sacnComponentId = createComponentId()
+
+func createComponentId() [16]byte {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+
+	//Random UUID (version 4)
+	id[6] = id[6]&0x0F | 0x40
+	id[8] = id[8]&0x3F | 0x80
+	return id
+}
+
+// encodeE131 creates an E1.31 data packet carrying the whole universe.
+func encodeE131(universeId uint16, sequence byte, priority byte, universe *Universe) []byte {
+	packet := make([]byte, 0, sacnPacketSize)
+
+	//Root layer
+	packet = append(packet, 0x00, 0x10, 0x00, 0x00)
+	packet = append(packet, sacnPacketId...)
+	packet = appendFlagsAndLength(packet, sacnPacketSize-16)
+	packet = appendUint32(packet, sacnRootVector)
+	packet = append(packet, sacnComponentId[:]...)
+
+	//Framing layer
+	packet = appendFlagsAndLength(packet, sacnPacketSize-38)
+	packet = appendUint32(packet, sacnFramingVector)
+	var sourceName [64]byte
+	copy(sourceName[:], sacnSourceName)
+	packet = append(packet, sourceName[:]...)
+	packet = append(packet, priority, 0x00, 0x00, sequence, 0x00)
+	packet = append(packet, byte(universeId>>8), byte(universeId))
+
+	//DMP layer
+	packet = appendFlagsAndLength(packet, sacnPacketSize-115)
+	packet = append(packet, sacnDmpVector, 0xA1, 0x00, 0x00, 0x00, 0x01)
+	packet = append(packet, byte((UniverseSize+1)>>8), byte((UniverseSize+1)&0xFF), dmxStartCode)
+	return append(packet, universe[:]...)
+}
+
+func appendFlagsAndLength(packet []byte, length int) []byte {
+	return append(packet, 0x70|byte(length>>8), byte(length))
+}
+
+func appendUint32(packet []byte, value uint32) []byte {
+	return append(packet, byte(value>>24), byte(value>>16), byte(value>>8), byte(value))
+}
+
+// getSacnMulticastAddress returns the multicast group receivers of the universe listen to.
+func getSacnMulticastAddress(universeId uint16) string {
+	return fmt.Sprintf("239.255.%d.%d", universeId>>8, universeId&0xFF)
+}
+
+func validateSacnUniverse(universeId uint16) error {
+	if universeId < minSacnUniverse || universeId > maxSacnUniverse {
+		return fmt.Errorf("sACN universe %d is outside of %d to %d", universeId, minSacnUniverse, maxSacnUniverse)
+	}
+
+	return nil
+}
+
+// decodeE131 returns the universe and channel data of an E1.31 data packet.
+// Terminated streams and packets that don't carry DMX channel data are ignored.
+func decodeE131(packet []byte) (uint16, []byte, bool) {
+	if len(packet) < 126 || string(packet[4:16]) != string(sacnPacketId) {
+		return 0, nil, false
+	}
+
+	rootVector := uint32(packet[18])<<24 | uint32(packet[19])<<16 | uint32(packet[20])<<8 | uint32(packet[21])
+	framingVector := uint32(packet[40])<<24 | uint32(packet[41])<<16 | uint32(packet[42])<<8 | uint32(packet[43])
+	if rootVector != sacnRootVector || framingVector != sacnFramingVector || packet[117] != sacnDmpVector {
+		return 0, nil, false
+	}
+
+	const streamTerminated = 0x40
+	if packet[112]&streamTerminated != 0 {
+		return 0, nil, false
+	}
+
+	universeId := uint16(packet[113])<<8 | uint16(packet[114])
+	count := int(packet[123])<<8 | int(packet[124])
+	if count < 1 || count > UniverseSize+1 || len(packet) < 125+count || packet[125] != dmxStartCode {
+		return 0, nil, false
+	}
+
+	return universeId, packet[126 : 125+count], true
+}
@@ -0,0 +1,98 @@
+package Dmx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeE131(t *testing.T) {
+	universe := createTestUniverse()
+	packet := encodeE131(0x0102, 42, 150, universe)
+
+	var sourceName [64]byte
+	copy(sourceName[:], sacnSourceName)
+
+	fields := []struct {
+		name     string
+		offset   int
+		expected []byte
+	}{
+		{"preamble size", 0, []byte{0x00, 0x10}},
+		{"postamble size", 2, []byte{0x00, 0x00}},
+		{"packet identifier", 4, []byte("ASC-E1.17\x00\x00\x00")},
+		{"root flags and length", 16, []byte{0x72, 0x6E}},
+		{"root vector", 18, []byte{0x00, 0x00, 0x00, 0x04}},
+		{"CID", 22, sacnComponentId[:]},
+		{"framing flags and length", 38, []byte{0x72, 0x58}},
+		{"framing vector", 40, []byte{0x00, 0x00, 0x00, 0x02}},
+		{"source name", 44, sourceName[:]},
+		{"priority", 108, []byte{150}},
+		{"sync address", 109, []byte{0x00, 0x00}},
+		{"sequence", 111, []byte{42}},
+		{"options", 112, []byte{0x00}},
+		{"universe", 113, []byte{0x01, 0x02}},
+		{"DMP flags and length", 115, []byte{0x72, 0x0B}},
+		{"DMP vector", 117, []byte{0x02}},
+		{"address and data type", 118, []byte{0xA1}},
+		{"first property address", 119, []byte{0x00, 0x00}},
+		{"address increment", 121, []byte{0x00, 0x01}},
+		{"property value count", 123, []byte{0x02, 0x01}},
+		{"start code", 125, []byte{0x00}},
+		{"data", 126, universe[:]},
+	}
+
+	if len(packet) != sacnPacketSize {
+		t.Fatalf("packet has %d bytes, expected %d", len(packet), sacnPacketSize)
+	}
+
+	for _, field := range fields {
+		if actual := packet[field.offset : field.offset+len(field.expected)]; !bytes.Equal(actual, field.expected) {
+			t.Errorf("%s at offset %d is % x, expected % x", field.name, field.offset, actual, field.expected)
+		}
+	}
+}
+
+func TestDecodeE131(t *testing.T) {
+	universe := createTestUniverse()
+	packet := encodeE131(0x0102, 42, sacnDefaultPriority, universe)
+
+	universeId, data, ok := decodeE131(packet)
+	if !ok || universeId != 0x0102 || !bytes.Equal(data, universe[:]) {
+		t.Errorf("decoding returned universe %d and %d channels, ok: %t", universeId, len(data), ok)
+	}
+
+	modify := func(offset int, values ...byte) []byte {
+		modified := append([]byte{}, packet...)
+		copy(modified[offset:], values)
+		return modified
+	}
+
+	tests := []struct {
+		name     string
+		packet   []byte
+		ok       bool
+		channels int
+	}{
+		{"fewer channels", modify(123, 0x00, 0x65)[:126+100], true, 100},
+		{"stream terminated", modify(112, 0x40), false, 0},
+		{"other start code", modify(125, 0xCC), false, 0},
+		{"synchronization packet", modify(40, 0x00, 0x00, 0x00, 0x01), false, 0},
+		{"other protocol", modify(4, 'B'), false, 0},
+		{"truncated", packet[:300], false, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, data, ok := decodeE131(test.packet)
+			if ok != test.ok || len(data) != test.channels {
+				t.Errorf("decoding returned %d channels, ok: %t, expected %d channels, ok: %t", len(data), ok, test.channels, test.ok)
+			}
+		})
+	}
+}
+
+func TestGetSacnMulticastAddress(t *testing.T) {
+	if address := getSacnMulticastAddress(0x0102); address != "239.255.1.2" {
+		t.Errorf("universe 258 is sent to %s, expected 239.255.1.2", address)
+	}
+}
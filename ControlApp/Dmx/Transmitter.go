@@ -0,0 +1,136 @@
+package Dmx
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Transmitter sends a single universe to the fixtures.
+type Transmitter interface {
+	Universe() uint16
+	Transmit(universe *Universe) error
+}
+
+// CreateTransmitter validates the output and prepares transmitting the universe with it.
+func CreateTransmitter(config OutputConfiguration) (Transmitter, error) {
+	switch config.Protocol {
+	case UsbWidget:
+		if config.Device == "" {
+			return nil, fmt.Errorf("USB widget of universe %d has no device", config.Universe)
+		}
+		return &widgetTransmitter{universe: config.Universe, device: config.Device}, nil
+	case ArtNet:
+		if err := validateArtNetUniverse(config.Universe); err != nil {
+			return nil, err
+		}
+
+		destination := config.Destination
+		if destination == "" {
+			destination = net.IPv4bcast.String()
+		}
+
+		return createNetworkTransmitter(config.Universe, destination, artNetPort, func(sequence byte, universe *Universe) []byte {
+			return encodeArtDmx(config.Universe, sequence, universe)
+		})
+	case Sacn:
+		if err := validateSacnUniverse(config.Universe); err != nil {
+			return nil, err
+		}
+
+		destination := config.Destination
+		if destination == "" {
+			destination = getSacnMulticastAddress(config.Universe)
+		}
+
+		priority := config.Priority
+		if priority == 0 {
+			priority = sacnDefaultPriority
+		}
+
+		return createNetworkTransmitter(config.Universe, destination, sacnPort, func(sequence byte, universe *Universe) []byte {
+			return encodeE131(config.Universe, sequence, priority, universe)
+		})
+	}
+
+	return nil, fmt.Errorf("unknown output protocol %q", config.Protocol)
+}
+
+// How long to wait before trying to reopen a lost USB widget
+const widgetReopenDelay = time.Second
+
+// widgetTransmitter outputs the universe through a USB widget, reopening it if it was lost.
+type widgetTransmitter struct {
+	universe    uint16
+	device      string
+	driver      *EnttecDriver
+	lastAttempt time.Time
+}
+
+func (transmitter *widgetTransmitter) Universe() uint16 {
+	return transmitter.universe
+}
+
+func (transmitter *widgetTransmitter) Transmit(universe *Universe) error {
+	if transmitter.driver == nil {
+		if time.Since(transmitter.lastAttempt) < widgetReopenDelay {
+			return fmt.Errorf("DMX widget %s is not connected", transmitter.device)
+		}
+
+		transmitter.lastAttempt = time.Now()
+		driver, err := OpenEnttecDriver(transmitter.device)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("DMX widget %s connected", transmitter.device)
+		transmitter.driver = driver
+	}
+
+	if err := transmitter.driver.Send(universe); err != nil {
+		_ = transmitter.driver.Close()
+		transmitter.driver = nil
+		return fmt.Errorf("DMX widget %s lost: %w", transmitter.device, err)
+	}
+
+	return nil
+}
+
+// networkTransmitter sends the universe as UDP packets to a node, a broadcast or a multicast address.
+type networkTransmitter struct {
+	universe   uint16
+	connection *net.UDPConn
+	sequence   byte
+	encode     func(sequence byte, universe *Universe) []byte
+}
+
+func createNetworkTransmitter(universeId uint16, host string, port int, encode func(sequence byte, universe *Universe) []byte) (*networkTransmitter, error) {
+	address, err := net.ResolveUDPAddr("udp4", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination of universe %d: %w", universeId, err)
+	}
+
+	connection, err := net.DialUDP("udp4", nil, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open socket for universe %d: %w", universeId, err)
+	}
+
+	return &networkTransmitter{universe: universeId, connection: connection, encode: encode}, nil
+}
+
+func (transmitter *networkTransmitter) Universe() uint16 {
+	return transmitter.universe
+}
+
+func (transmitter *networkTransmitter) Transmit(universe *Universe) error {
+	//Art-Net reserves sequence number 0 for disabling the reordering on the receivers
+	transmitter.sequence++
+	if transmitter.sequence == 0 {
+		transmitter.sequence = 1
+	}
+
+	_, err := transmitter.connection.Write(transmitter.encode(transmitter.sequence, universe))
+	return err
+}
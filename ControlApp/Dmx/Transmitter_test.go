@@ -0,0 +1,139 @@
+package Dmx
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// listenLocally opens a UDP listener on the loopback interface as a stand-in for a node.
+func listenLocally(t *testing.T, port int) *net.UDPConn {
+	listener, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Skipf("can't listen on UDP port %d: %s", port, err)
+	}
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	return listener
+}
+
+// receivePacket waits for the next packet sent to the listener.
+func receivePacket(t *testing.T, listener *net.UDPConn) []byte {
+	buffer := make([]byte, 1500)
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+
+	n, _, err := listener.ReadFromUDP(buffer)
+	if err != nil {
+		t.Fatalf("no packet received: %s", err)
+	}
+
+	return buffer[:n]
+}
+
+func TestNetworkTransmitter(t *testing.T) {
+	universe := createTestUniverse()
+
+	tests := []struct {
+		name   string
+		encode func(sequence byte, universe *Universe) []byte
+		decode func(packet []byte) (uint16, []byte, bool)
+	}{
+		{
+			name: "Art-Net",
+			encode: func(sequence byte, universe *Universe) []byte {
+				return encodeArtDmx(7, sequence, universe)
+			},
+			decode: decodeArtDmx,
+		},
+		{
+			name: "sACN",
+			encode: func(sequence byte, universe *Universe) []byte {
+				return encodeE131(7, sequence, sacnDefaultPriority, universe)
+			},
+			decode: decodeE131,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			listener := listenLocally(t, 0)
+			port := listener.LocalAddr().(*net.UDPAddr).Port
+
+			transmitter, err := createNetworkTransmitter(7, "127.0.0.1", port, test.encode)
+			if err != nil {
+				t.Fatalf("creating the transmitter failed: %s", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := transmitter.Transmit(universe); err != nil {
+					t.Fatalf("transmitting failed: %s", err)
+				}
+
+				packet := receivePacket(t, listener)
+				universeId, data, ok := test.decode(packet)
+				if !ok || universeId != 7 || !bytes.Equal(data, universe[:]) {
+					t.Fatalf("received universe %d with %d channels, ok: %t", universeId, len(data), ok)
+				}
+			}
+		})
+	}
+}
+
+func TestNetworkTransmitterSkipsSequenceZero(t *testing.T) {
+	listener := listenLocally(t, 0)
+	port := listener.LocalAddr().(*net.UDPAddr).Port
+
+	transmitter, err := createNetworkTransmitter(1, "127.0.0.1", port, func(sequence byte, universe *Universe) []byte {
+		return encodeArtDmx(1, sequence, universe)
+	})
+	if err != nil {
+		t.Fatalf("creating the transmitter failed: %s", err)
+	}
+
+	transmitter.sequence = 0xFF
+	if err := transmitter.Transmit(&Universe{}); err != nil {
+		t.Fatalf("transmitting failed: %s", err)
+	}
+
+	if sequence := receivePacket(t, listener)[12]; sequence != 1 {
+		t.Errorf("sequence wrapped around to %d, expected 1", sequence)
+	}
+}
+
+func TestCreateTransmitterArtNet(t *testing.T) {
+	listener := listenLocally(t, artNetPort)
+
+	transmitter, err := CreateTransmitter(OutputConfiguration{Protocol: ArtNet, Universe: 3, Destination: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("creating the transmitter failed: %s", err)
+	}
+
+	universe := createTestUniverse()
+	if err := transmitter.Transmit(universe); err != nil {
+		t.Fatalf("transmitting failed: %s", err)
+	}
+
+	universeId, data, ok := decodeArtDmx(receivePacket(t, listener))
+	if !ok || universeId != 3 || !bytes.Equal(data, universe[:]) {
+		t.Errorf("received universe %d with %d channels, ok: %t", universeId, len(data), ok)
+	}
+}
+
+func TestCreateTransmitterValidatesUniverse(t *testing.T) {
+	tests := []OutputConfiguration{
+		{Protocol: ArtNet, Universe: maxArtNetUniverse + 1},
+		{Protocol: Sacn, Universe: 0},
+		{Protocol: Sacn, Universe: maxSacnUniverse + 1},
+		{Protocol: UsbWidget, Universe: 1},
+		{Protocol: "dmx-over-carrier-pigeon", Universe: 1},
+	}
+
+	for _, config := range tests {
+		if _, err := CreateTransmitter(config); err == nil {
+			t.Errorf("invalid output %+v was accepted", config)
+		}
+	}
+}
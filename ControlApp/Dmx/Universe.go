@@ -0,0 +1,120 @@
+package Dmx
+
+import (
+	"ControlApp/BoxiBus"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const UniverseSize = 512
+
+// Universe holds the channel values of a DMX512 universe. Channel 1 is stored at index 0.
+type Universe [UniverseSize]byte
+
+// ChannelFunction describes what a single channel of a fixture controls.
+type ChannelFunction byte
+
+const (
+	Unused ChannelFunction = iota
+	Red
+	Green
+	Blue
+	White
+	Amber
+	UltraViolet
+	Dimmer
+)
+
+var channelFunctionNames = map[ChannelFunction]string{
+	Unused:      "unused",
+	Red:         "red",
+	Green:       "green",
+	Blue:        "blue",
+	White:       "white",
+	Amber:       "amber",
+	UltraViolet: "uv",
+	Dimmer:      "dimmer",
+}
+
+func (function ChannelFunction) String() string {
+	if name, ok := channelFunctionNames[function]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ChannelFunction(%d)", byte(function))
+}
+
+func (function ChannelFunction) MarshalText() ([]byte, error) {
+	if _, ok := channelFunctionNames[function]; !ok {
+		return nil, fmt.Errorf("unknown channel function %d", byte(function))
+	}
+
+	return []byte(function.String()), nil
+}
+
+func (function *ChannelFunction) UnmarshalText(text []byte) error {
+	for candidate, name := range channelFunctionNames {
+		if strings.EqualFold(name, string(text)) {
+			*function = candidate
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown channel function %q", text)
+}
+
+// Fixture is a DMX fixture patched into the universe. It shows the lighting of one of the Boxis.
+type Fixture struct {
+	Name     string
+	Universe uint16                 //The universe the fixture is patched into
+	Address  uint16                 //First channel of the fixture, starting at 1
+	Channels []ChannelFunction      //The channel profile of the fixture, in the order of its channels
+	Boxi     BoxiBus.LightingTarget //The Boxi whose lighting the fixture follows
+}
+
+// Validate checks that the fixture fits into the universe and follows a single Boxi.
+func (fixture Fixture) Validate() error {
+	if len(fixture.Channels) == 0 {
+		return errors.New("fixture has no channels")
+	}
+
+	if fixture.Address < 1 || int(fixture.Address)+len(fixture.Channels)-1 > UniverseSize {
+		return fmt.Errorf("channels %d to %d are outside of the universe", fixture.Address, int(fixture.Address)+len(fixture.Channels)-1)
+	}
+
+	if fixture.Boxi != BoxiBus.TargetBoxi1 && fixture.Boxi != BoxiBus.TargetBoxi2 {
+		return fmt.Errorf("fixture has to follow either Boxi 1 or Boxi 2, not %d", fixture.Boxi)
+	}
+
+	return nil
+}
+
+// SetFixtureColor writes the color into the channels of a validated fixture.
+func (universe *Universe) SetFixtureColor(fixture Fixture, color BoxiBus.Color) {
+	for i, function := range fixture.Channels {
+		universe[int(fixture.Address)-1+i] = getChannelValue(function, color)
+	}
+}
+
+func getChannelValue(function ChannelFunction, color BoxiBus.Color) byte {
+	switch function {
+	case Red:
+		return color.Red
+	case Green:
+		return color.Green
+	case Blue:
+		return color.Blue
+	case White:
+		return color.White
+	case Amber:
+		return color.Amber
+	case UltraViolet:
+		return color.UltraViolet
+	case Dimmer:
+		//The brightness is already part of the color channels
+		return 255
+	}
+
+	return 0
+}
@@ -4,7 +4,6 @@ import (
 	"ControlApp/Api"
 	"ControlApp/BoxiBus"
 	"ControlApp/Display"
-	"ControlApp/Infrastructure"
 	"ControlApp/Lightshow"
 	"fmt"
 	"math"
@@ -34,8 +33,10 @@ type textInformation struct {
 
 type overridePageInformation struct {
 	ScaffoldInformation
+	LightingDeskInControl   bool
 	LightingOverride        bool
 	LightingMode            int
+	LightingExtendedModes   bool //Whether the firmware of the Arduino knows the chase, rainbow cycle and breathing modes
 	LightingShowColorA      bool
 	LightingShowColorB      bool
 	LightingColorA          string
@@ -53,6 +54,10 @@ type overridePageInformation struct {
 	LightingSpeedValue      int
 	LightingShowShift       bool
 	LightingShiftValue      int
+	LightingShowHueOffset   bool
+	LightingHueOffsetValue  int
+	LightingShowSaturation  bool
+	LightingSaturationValue int
 	AnimationsOverride      bool
 	Animations              []Lightshow.Animation
 	AnimationsSelected      []animationInformation
@@ -63,17 +68,27 @@ type overridePageInformation struct {
 
 type palettePageInformation struct {
 	ScaffoldInformation
-	Palettes []Lightshow.Palette
+	Palettes        []Lightshow.Palette
+	ColorSlots      []int
+	SupportedColors int //The most colors the firmware of the Arduino shows, fewer than the slots for old firmware
+	MaxWeight       float64
 }
 
 type autoModePageInformation struct {
 	ScaffoldInformation
 	Api.AutoModeConfig
+	PerBoxiLighting bool //Whether the firmware of the Arduino runs a separate lighting program for each Boxi
+}
+
+type schedulePageInformation struct {
+	ScaffoldInformation
+	Api.ScheduleData
 }
 
 type animationsPageInformation struct {
 	ScaffoldInformation
 	Animations []animationInstance
+	MaxWeight  float64
 }
 
 type animationInstance struct {
@@ -81,6 +96,7 @@ type animationInstance struct {
 	Name      string
 	Details   string
 	Thumbnail string
+	Weight    float64
 }
 
 func (Me PageProvider) HandleStartPage(w http.ResponseWriter, r *http.Request) {
@@ -111,15 +127,20 @@ func (Me PageProvider) HandleOverridesPage(w http.ResponseWriter, r *http.Reques
 	//Fetch scaffold data from context
 	scaffoldData := GetScaffoldData(r)
 
-	mode := BoxiBus.LightingModeId(Me.Data.OverrideLightingCurrent.Mode)
+	lighting, deskInControl := Me.Data.GetLightingOverride()
+	mode := BoxiBus.LightingModeId(lighting.Mode)
 	showColorA := mode == BoxiBus.SetColor || mode == BoxiBus.FadeToColor || mode == BoxiBus.Strobe
 	showColorB := mode == BoxiBus.SetColor || mode == BoxiBus.FadeToColor
-	showPalette := mode == BoxiBus.PaletteFade || mode == BoxiBus.PaletteSwitch || mode == BoxiBus.PaletteBrightnessFlash || mode == BoxiBus.PaletteHueFlash
-	showDuration := mode == BoxiBus.FadeToColor || mode == BoxiBus.PaletteFade
-	showBrightness := mode == BoxiBus.PaletteBrightnessFlash
+	showPalette := mode == BoxiBus.PaletteFade || mode == BoxiBus.PaletteSwitch || mode == BoxiBus.PaletteBrightnessFlash || mode == BoxiBus.PaletteHueFlash ||
+		mode == BoxiBus.Chase || mode == BoxiBus.Breathing
+	showDuration := mode == BoxiBus.FadeToColor || mode == BoxiBus.PaletteFade || mode == BoxiBus.RainbowCycle || mode == BoxiBus.Breathing
+	showBrightness := mode == BoxiBus.PaletteBrightnessFlash || mode == BoxiBus.Chase || mode == BoxiBus.Breathing
 	showSpeed := mode == BoxiBus.PaletteBrightnessFlash || mode == BoxiBus.PaletteHueFlash
-	showShift := mode == BoxiBus.PaletteFade || mode == BoxiBus.PaletteSwitch || mode == BoxiBus.PaletteBrightnessFlash || mode == BoxiBus.PaletteHueFlash
+	showShift := mode == BoxiBus.PaletteFade || mode == BoxiBus.PaletteSwitch || mode == BoxiBus.PaletteBrightnessFlash || mode == BoxiBus.PaletteHueFlash ||
+		mode == BoxiBus.Breathing
 	showFrequency := mode == BoxiBus.Strobe
+	showHueOffset := mode == BoxiBus.RainbowCycle
+	showSaturation := mode == BoxiBus.RainbowCycle
 
 	var animations []animationInformation
 	for _, anim := range Me.Data.OverrideAnimationCurrent.Animations {
@@ -168,25 +189,31 @@ func (Me PageProvider) HandleOverridesPage(w http.ResponseWriter, r *http.Reques
 
 	data := overridePageInformation{
 		ScaffoldInformation:     scaffoldData,
-		LightingOverride:        Me.Data.OverrideLightingCurrent.Enable,
-		LightingMode:            Me.Data.OverrideLightingCurrent.Mode,
+		LightingDeskInControl:   deskInControl,
+		LightingOverride:        lighting.Enable,
+		LightingMode:            lighting.Mode,
+		LightingExtendedModes:   Me.Data.Visuals.SupportsLightingMode(BoxiBus.Chase),
 		LightingShowColorA:      showColorA,
-		LightingColorA:          getColorString(Me.Data.OverrideLightingCurrent.ColorDeviceA),
+		LightingColorA:          getColorString(lighting.ColorDeviceA),
 		LightingShowColorB:      showColorB,
-		LightingColorB:          getColorString(Me.Data.OverrideLightingCurrent.ColorDeviceB),
+		LightingColorB:          getColorString(lighting.ColorDeviceB),
 		LightingShowPalettes:    showPalette,
 		LightingPalettes:        Me.Data.Visuals.GetPalettes().GetAll(),
-		LightingPaletteId:       Me.Data.OverrideLightingCurrent.PaletteId,
+		LightingPaletteId:       lighting.PaletteId,
 		LightingShowDuration:    showDuration,
-		LightingDurationValue:   Me.Data.OverrideLightingCurrent.DurationMs,
+		LightingDurationValue:   lighting.DurationMs,
 		LightingShowBrightness:  showBrightness,
-		LightingBrightnessValue: Me.Data.OverrideLightingCurrent.TargetBrightness,
+		LightingBrightnessValue: lighting.TargetBrightness,
 		LightingShowFrequency:   showFrequency,
-		LightingFrequencyValue:  Me.Data.OverrideLightingCurrent.FrequencyHz,
+		LightingFrequencyValue:  lighting.FrequencyHz,
 		LightingShowShift:       showShift,
-		LightingShiftValue:      Me.Data.OverrideLightingCurrent.PaletteShift,
+		LightingShiftValue:      lighting.PaletteShift,
 		LightingShowSpeed:       showSpeed,
-		LightingSpeedValue:      Me.Data.OverrideLightingCurrent.Speed,
+		LightingSpeedValue:      lighting.Speed,
+		LightingShowHueOffset:   showHueOffset,
+		LightingHueOffsetValue:  lighting.HueOffset,
+		LightingShowSaturation:  showSaturation,
+		LightingSaturationValue: lighting.Saturation,
 		AnimationsOverride:      !Me.Data.OverrideAnimationCurrent.ResetScreens,
 		Animations:              allAnimations,
 		AnimationsSelected:      animations,
@@ -241,13 +268,14 @@ func (Me PageProvider) HandleAnimationPage(w http.ResponseWriter, r *http.Reques
 			Name:      animation.Name,
 			Details:   moodStr + ", " + nsfwStr,
 			Thumbnail: fmt.Sprintf("/static/thumbs/%d.png", animation.Id),
+			Weight:    animation.GetWeight(),
 		}
 		animations = append(animations, aniInstance)
 	}
 
 	//Fetch scaffold data from context
 	scaffoldData := GetScaffoldData(r)
-	templateData := animationsPageInformation{scaffoldData, animations}
+	templateData := animationsPageInformation{scaffoldData, animations, Lightshow.MaxWeight}
 
 	//Disable caching
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -264,7 +292,12 @@ func (Me PageProvider) HandleAnimationPage(w http.ResponseWriter, r *http.Reques
 func (Me PageProvider) HandlePalettesPage(w http.ResponseWriter, r *http.Request) {
 	//Fetch scaffold data from context
 	scaffoldData := GetScaffoldData(r)
-	templateData := palettePageInformation{scaffoldData, Me.Data.Visuals.GetPalettes().GetAll()}
+	colorSlots := make([]int, BoxiBus.MaxPaletteSize)
+	for i := range colorSlots {
+		colorSlots[i] = i + 1
+	}
+	supportedColors := Me.Data.Hardware.GetConnectionState().MaxPaletteSize()
+	templateData := palettePageInformation{scaffoldData, Me.Data.Visuals.GetPalettes().GetAll(), colorSlots, supportedColors, Lightshow.MaxWeight}
 
 	//Disable caching
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -286,13 +319,17 @@ func (Me PageProvider) HandleAutoPage(w http.ResponseWriter, r *http.Request) {
 	configData := Api.AutoModeConfig{
 		StrobeChance:               rawConfig.StrobeChance,
 		HueShiftChance:             rawConfig.HueShiftChance,
-		FadeToColorMs:              uint16(math.Round(float64(rawConfig.FadeToColorCycles) / Infrastructure.FadeDurationMsToCycles)),
-		PaletteFadeMs:              uint16(math.Round(float64(rawConfig.PaletteFadeCycles) / Infrastructure.FadeDurationMsToCycles)),
+		FadeToColorMs:              uint16(Lightshow.CyclesToFadeDuration(rawConfig.FadeToColorCycles).Milliseconds()),
+		PaletteFadeMs:              uint16(Lightshow.CyclesToFadeDuration(rawConfig.PaletteFadeCycles).Milliseconds()),
 		FlashFadeoutSpeed:          rawConfig.FlashFadeoutSpeed,
 		HueFlashFadeoutSpeed:       rawConfig.HueFlashFadeoutSpeed,
-		StrobeFrequency:            uint16(math.Round(Infrastructure.StrobeFrequencyMultiplier / float64(rawConfig.StrobeFrequency))),
-		FlashTargetBrightness:      byte(math.Round(float64(rawConfig.FlashTargetBrightness) / 255 * 100)),
+		StrobeFrequency:            uint16(math.Round(Lightshow.StrobeSpeedToFrequency(rawConfig.StrobeFrequency))),
+		FlashTargetBrightness:      byte(Lightshow.ByteToPercent(rawConfig.FlashTargetBrightness)),
 		FlashHueShift:              rawConfig.FlashHueShift,
+		IndependentBoxis:           rawConfig.IndependentBoxis,
+		PhraseAligned:              rawConfig.PhraseAligned,
+		BeatsPerBar:                rawConfig.BeatsPerBar,
+		BarsPerPhrase:              rawConfig.BarsPerPhrase,
 		MinTimeBetweenBeatsMs:      uint16(rawConfig.MinTimeBetweenBeats.Milliseconds()),
 		LightingCalmModeBoringSec:  uint16(rawConfig.LightingCalmModeBoring.Seconds()),
 		AnimationCalmModeBoringSec: uint16(rawConfig.AnimationCalmModeBoring.Seconds()),
@@ -305,6 +342,7 @@ func (Me PageProvider) HandleAutoPage(w http.ResponseWriter, r *http.Request) {
 	templateData := autoModePageInformation{
 		ScaffoldInformation: scaffoldData,
 		AutoModeConfig:      configData,
+		PerBoxiLighting:     Me.Data.Visuals.SupportsPerBoxiLighting(),
 	}
 
 	//Disable caching
@@ -326,3 +364,30 @@ func getApiTimingConstraint(constraint Lightshow.TimingConstraint) Api.TimingCon
 		NoBeatDeadTimeSec: constraint.NoBeatDeadTime.Seconds(),
 	}
 }
+
+func (Me PageProvider) HandleSchedulePage(w http.ResponseWriter, r *http.Request) {
+	schedule := Me.Data.Visuals.GetSchedule().GetSchedule()
+	scheduleData := Api.ScheduleData{Enabled: schedule.Enabled}
+	for _, program := range schedule.Programs {
+		scheduleData.Programs = append(scheduleData.Programs, Api.ShowProgram{
+			Start:      program.Start,
+			Mood:       int(program.Mood),
+			AllowNsfw:  program.AllowNsfw,
+			Brightness: program.Brightness,
+		})
+	}
+
+	//Fetch scaffold data from context
+	scaffoldData := GetScaffoldData(r)
+	templateData := schedulePageInformation{scaffoldData, scheduleData}
+
+	//Disable caching
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	//Execute template
+	err := Me.schedulePage.Execute(w, templateData)
+	if err != nil {
+		fmt.Print(err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -15,6 +15,7 @@ type PageProvider struct {
 	animationsPage *template.Template
 	palettesPage   *template.Template
 	autoPage       *template.Template
+	schedulePage   *template.Template
 }
 
 type ScaffoldInformation struct {
@@ -30,6 +31,7 @@ func CreatePageProvider(data *Api.DataContainer) PageProvider {
 	animations := template.Must(template.ParseFiles("Frontend/template/scaffold.gohtml", "Frontend/template/animations.gohtml"))
 	palettes := template.Must(template.ParseFiles("Frontend/template/scaffold.gohtml", "Frontend/template/palettes.gohtml"))
 	auto := template.Must(template.ParseFiles("Frontend/template/scaffold.gohtml", "Frontend/template/auto.gohtml"))
+	schedule := template.Must(template.ParseFiles("Frontend/template/scaffold.gohtml", "Frontend/template/schedule.gohtml"))
 
 	return PageProvider{
 		Data:           data,
@@ -38,6 +40,7 @@ func CreatePageProvider(data *Api.DataContainer) PageProvider {
 		animationsPage: animations,
 		palettesPage:   palettes,
 		autoPage:       auto,
+		schedulePage:   schedule,
 	}
 }
 
@@ -59,6 +62,8 @@ func GetPageTitle(pageName string) string {
 		return "Palettes"
 	case "auto":
 		return "Auto Mode Settings"
+	case "schedule":
+		return "Show Schedule"
 	}
 
 	return "Boxi Control App"
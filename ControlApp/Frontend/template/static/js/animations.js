@@ -58,6 +58,20 @@ $('.animation-item-delete').on('click', async e => {
 });
 
 
+$('.animation-item-weight').on('change', async e => {
+    const itemContainer = e.target.closest('.animation-item');
+    const id = parseInt(itemContainer.getAttribute('animation-id'));
+
+    const response = await fetch(baseAddr + 'api/animation?' + new URLSearchParams({
+        id: id,
+        weight: e.target.value
+    }), {method: 'PUT'});
+
+    if (response.status !== 200) {
+        alert("Error submitting weight change: " + await response.text());
+    }
+});
+
 async function deleteAnimation(id) {
     await fetch(baseAddr + 'api/animation?id=' + id, {
         method: 'DELETE'
@@ -9,6 +9,10 @@ $('#auto-config-save').on('click', async () => {
         strobeFrequency: parseInt($('#auto-config-strobe-frequency')[0].value),
         brightnessFlashBrightness: parseInt($('#auto-config-target-brightness-flash')[0].value),
         hueFlashShift: parseInt($('#auto-config-color-span-hue-flash')[0].value),
+        independentBoxis: $('#auto-config-independent-boxis')[0].checked,
+        phraseAligned: $('#auto-config-phrase-aligned')[0].checked,
+        beatsPerBar: parseInt($('#auto-config-beats-per-bar')[0].value),
+        barsPerPhrase: parseInt($('#auto-config-bars-per-phrase')[0].value),
         minTimeBetweenBeats: parseInt($('#auto-config-beat-cooldown-time')[0].value),
         timeBeforeLightingBoring: parseInt($('#auto-config-calm-lighting-boring')[0].value),
         timeBeforeAnimationBoring: parseInt($('#auto-config-calm-animation-boring')[0].value),
@@ -1,14 +1,5 @@
 const channels = ['R', 'G', 'B', 'W', 'A', 'U'];
-const values = [
-    {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0},
-    {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0},
-    {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0},
-    {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0},
-    {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0},
-    {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0},
-    {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0},
-    {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0},
-];
+const values = [];
 
 const targets = {
     W: [255, 255, 255],
@@ -24,6 +15,7 @@ $(".colorpicker").each(function (index, sliderContainer) {
     let initialValues = getColorArrayFromPicker(sliderContainer);
 
     // Initialize values
+    values[index] = {R: 0, G: 0, B: 0, W: 0, A: 0, U: 0};
     channels.forEach((channel, i) => {
         values[index][channel] = initialValues[i];
     });
@@ -7,7 +7,9 @@ const lightingModeOptions = [
     $('#overwrite-lighting-frequency-row')[0],
     $('#overwrite-lighting-brightness-row')[0],
     $('#overwrite-lighting-speed-row')[0],
-    $('#overwrite-lighting-shift-row')[0]
+    $('#overwrite-lighting-shift-row')[0],
+    $('#overwrite-lighting-hue-offset-row')[0],
+    $('#overwrite-lighting-saturation-row')[0]
 ];
 
 const lightingModeSelector = $('#overwrite-lighting-mode')[0];
@@ -69,6 +71,15 @@ lightingModeSelector.onchange = _ => {
         case 7: //Strobe
             setLightingModeOptions([1, 4]);
             break;
+        case 8: //Chase
+            setLightingModeOptions([0, 5]);
+            break;
+        case 9: //Rainbow cycle
+            setLightingModeOptions([3, 8, 9]);
+            break;
+        case 10: //Breathing
+            setLightingModeOptions([0, 3, 5, 7]);
+            break;
         default: //Off
             setLightingModeOptions([]);
     }
@@ -109,7 +120,9 @@ async function sendLightingOverwrite(reset, onBeat) {
         paletteShift: parseInt($('#overwrite-lighting-shift')[0].value),
         speed: parseInt($('#overwrite-lighting-speed')[0].value),
         targetBrightness: parseInt($('#overwrite-lighting-brightness')[0].value),
-        frequency: parseInt($('#overwrite-lighting-frequency')[0].value)
+        frequency: parseInt($('#overwrite-lighting-frequency')[0].value),
+        hueOffset: parseInt($('#overwrite-lighting-hue-offset')[0].value),
+        saturation: parseInt($('#overwrite-lighting-saturation')[0].value)
     }
 
     await fetch(baseAddr + 'api/lighting/mode', {
@@ -152,4 +165,35 @@ async function sendTextsOverwrite(reset) {
         method: 'POST',
         body: JSON.stringify(returnObj),
     });
-}
\ No newline at end of file
+}
+
+//Show what the Boxis are doing right now
+const lightingPreview = new EventSource(baseAddr + 'api/lighting/preview');
+lightingPreview.onmessage = e => {
+    const preview = JSON.parse(e.data);
+    updateLightingPreview(1, preview.boxi1);
+    updateLightingPreview(2, preview.boxi2);
+};
+
+function updateLightingPreview(boxi, state) {
+    const color = state.color;
+    const blend = {r: color.R, g: color.G, b: color.B};
+
+    //Mix the extra channels in like the color picker preview does
+    [['W', color.W], ['A', color.A], ['U', color.UV]].forEach(([channel, value]) => {
+        const t = value / 255 * 0.5;
+        const [tr, tg, tb] = targets[channel];
+
+        blend.r = lerp(blend.r, tr, t);
+        blend.g = lerp(blend.g, tg, t);
+        blend.b = lerp(blend.b, tb, t);
+    });
+
+    const r = Math.round(Math.min(255, blend.r));
+    const g = Math.round(Math.min(255, blend.g));
+    const b = Math.round(Math.min(255, blend.b));
+    $('#lighting-preview-boxi-' + boxi)[0].style.backgroundColor = `rgb(${r}, ${g}, ${b})`;
+
+    const modeOption = $(`#overwrite-lighting-mode option[value="${state.mode}"]`)[0];
+    $('#lighting-preview-mode-' + boxi)[0].textContent = modeOption ? modeOption.textContent : "";
+}
@@ -2,27 +2,10 @@
 const contentPanel = $('#palette-content')[0];
 const countSelector = $('#palette-count')[0];
 const nameInput = $('#palette-name')[0];
+const weightInput = $('#palette-weight')[0];
 const itemSelection = $('#itemSelection')[0];
-const colorPickers = [
-    $('#palette-color-a')[0],
-    $('#palette-color-b')[0],
-    $('#palette-color-c')[0],
-    $('#palette-color-d')[0],
-    $('#palette-color-e')[0],
-    $('#palette-color-f')[0],
-    $('#palette-color-g')[0],
-    $('#palette-color-h')[0],
-];
-const colorPickerRows = [
-    $('#palette-color-a-row')[0],
-    $('#palette-color-b-row')[0],
-    $('#palette-color-c-row')[0],
-    $('#palette-color-d-row')[0],
-    $('#palette-color-e-row')[0],
-    $('#palette-color-f-row')[0],
-    $('#palette-color-g-row')[0],
-    $('#palette-color-h-row')[0],
-];
+const colorPickers = $('.palette-color').toArray();
+const colorPickerRows = $('.palette-color-row').toArray();
 const moodCheckboxes = [
     $('#palette-mood-happy')[0],
     $('#palette-mood-moody')[0],
@@ -41,6 +24,7 @@ itemSelection.onchange = async () => {
     const paletteData = await getColors(currentPalette);
     countSelector.value = paletteData.colors.length;
     nameInput.value = paletteData.name;
+    weightInput.value = paletteData.weight;
 
     for (let i = 0; i < 4; i++) {
         moodCheckboxes[i].checked = false;
@@ -50,7 +34,7 @@ itemSelection.onchange = async () => {
         moodCheckboxes[paletteData.moods[i]].checked = true;
     }
 
-    for (let i = 0; i < 8; i++) {
+    for (let i = 0; i < colorPickers.length; i++) {
         if (i >= paletteData.colors.length) {
             colorPickerRows[i].style.display = "none";
             continue;
@@ -77,9 +61,9 @@ $('#palette-save').on('click', async _ => {
 
 countSelector.onchange = () => {
     const value = parseInt(countSelector.value);
-    if (value < 1 || value > 8) return;
+    if (value < 1 || value > colorPickers.length) return;
 
-    for (let i = 0; i<8; i++) {
+    for (let i = 0; i<colorPickers.length; i++) {
         const pickerRow = colorPickerRows[i];
         pickerRow.style.display = i < value ? "initial" : "none";
     }
@@ -164,13 +148,18 @@ async function saveChange() {
         id: currentPalette,
         name: nameInput.value,
         moods: moods,
-        colors: color
+        colors: color,
+        weight: parseFloat(weightInput.value) || 0
     }
 
-    await fetch(baseAddr + 'api/palette', {
+    const response = await fetch(baseAddr + 'api/palette', {
         method: 'PUT',
         body: JSON.stringify(data)
     });
+
+    if (!response.ok) {
+        alert("Error saving the palette: " + await response.text());
+    }
 }
 
 async function createPalette(name) {
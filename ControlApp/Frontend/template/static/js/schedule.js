@@ -0,0 +1,33 @@
+$('#schedule-add').on('click', () => {
+    const row = $('#schedule-program-template')[0].content.firstElementChild.cloneNode(true);
+    $('#schedule-programs')[0].tBodies[0].appendChild(row);
+});
+
+$('#schedule-programs').on('click', '.schedule-program-remove', e => {
+    e.target.closest('.schedule-program').remove();
+});
+
+$('#schedule-save').on('click', async () => {
+    const programs = $('.schedule-program').toArray().map(row => ({
+        start: row.querySelector('.schedule-program-start').value,
+        mood: parseInt(row.querySelector('.schedule-program-mood').value),
+        nsfw: row.querySelector('.schedule-program-nsfw').checked,
+        brightness: parseInt(row.querySelector('.schedule-program-brightness').value),
+    }));
+
+    const response = await fetch(baseAddr + 'api/schedule', {
+        method: 'PUT',
+        body: JSON.stringify({
+            enabled: $('#schedule-enabled')[0].checked,
+            programs: programs,
+        }),
+    });
+
+    if (response.status !== 200) {
+        alert("Error saving the schedule: " + await response.text());
+        return;
+    }
+
+    //Show the programs sorted by their start
+    location.reload();
+});
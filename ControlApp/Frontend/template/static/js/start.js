@@ -2,6 +2,15 @@ $('#mood').change(moodChanged)
 $('#nsfw').change(nsfwChanged)
 $('#internal-leds').change(internalLedsChanged)
 $('#brightness').change(brightnessChanged)
+$('#preset-recall').on('click', recallPreset)
+$('#preset-save').on('click', savePreset)
+$('#preset-delete').on('click', deletePreset)
+
+setInterval(updateTempo, 1000)
+updateTempo()
+setInterval(updateAutoState, 1000)
+updateAutoState()
+updatePresets()
 
 async function moodChanged(e) {
     const selected = e.target.selectedOptions[0];
@@ -40,6 +49,73 @@ async function brightnessChanged(e) {
     }
 }
 
+async function updatePresets(selected) {
+    const response = await fetch('/api/presets');
+    if (response.status !== 200) return;
+
+    const presetSelection = $('#preset')[0];
+    presetSelection.replaceChildren();
+    for (const preset of (await response.json()).presets) {
+        const option = document.createElement("option");
+        option.value = preset.name;
+        option.innerText = preset.name;
+        presetSelection.appendChild(option);
+    }
+
+    if (selected !== undefined) presetSelection.value = selected;
+}
+
+async function recallPreset() {
+    const name = $('#preset')[0].value;
+    if (name === "") return;
+
+    const response = await fetch('/api/preset/recall?' + new URLSearchParams({
+        name: name,
+        fade: $('#preset-fade')[0].value
+    }), {method: 'POST'});
+
+    if (response.status !== 200) {
+        alert("Error recalling preset: " + await response.text());
+        return;
+    }
+
+    //The preset may have changed the mood and NSFW allowance
+    location.reload();
+}
+
+async function savePreset() {
+    const name = prompt("Please enter the name of the preset, an existing one is replaced:", $('#preset')[0].value);
+    if (name === null) return;
+
+    const response = await fetch('/api/preset?' + new URLSearchParams({
+        name: name
+    }), {method: 'POST'});
+
+    if (response.status !== 200) {
+        alert("Error saving preset: " + await response.text());
+        return;
+    }
+
+    await updatePresets(name);
+}
+
+async function deletePreset() {
+    const name = $('#preset')[0].value;
+    if (name === "") return;
+    if (!confirm("Do you really want to delete preset '" + name + "'?")) return;
+
+    const response = await fetch('/api/preset?' + new URLSearchParams({
+        name: name
+    }), {method: 'DELETE'});
+
+    if (response.status !== 200) {
+        alert("Error deleting preset: " + await response.text());
+        return;
+    }
+
+    await updatePresets();
+}
+
 async function sendMoodChange(mood) {
     const response = await fetch('/api/config/mood?' + new URLSearchParams({
         value: mood
@@ -66,4 +142,54 @@ async function sendBrightnessChange(value) {
         value: value
     }), {method: 'POST'});
     return response.status === 200 ? null : await response.text();
-}
\ No newline at end of file
+}
+
+async function updateTempo() {
+    const response = await fetch('/api/tempo');
+    if (response.status !== 200) return;
+
+    const tempo = await response.json();
+    $('#tempo')[0].textContent = tempo.bpm === 0 ? "Unknown" :
+        `${Math.round(tempo.bpm)} BPM (${Math.round(tempo.confidence * 100)} % confidence)`;
+}
+
+async function updateAutoState() {
+    const response = await fetch('/api/auto/state');
+    if (response.status !== 200) return;
+
+    const state = await response.json();
+    const now = new Date(state.now);
+    const secondsFromNow = time => ((new Date(time) - now) / 1000).toFixed(1);
+
+    let phase = state.running ? state.phase : "Stopped";
+    if (state.paused) phase += " (paused)";
+    $('#auto-state')[0].textContent = phase + (state.wasInCalmMode ? ", calm since the last beat" : "");
+
+    $('#auto-last-beat')[0].textContent = state.lastBeat === null ? "None" : `${-secondsFromNow(state.lastBeat)} s ago`;
+    $('#auto-lighting-switch')[0].textContent =
+        describeSwitch(state.lightingBeatsLeft, state.lightingDeadline, state.lightingBoringInSec, secondsFromNow);
+    $('#auto-animation-switch')[0].textContent =
+        describeSwitch(state.animationBeatsLeft, state.animationDeadline, state.animationBoringInSec, secondsFromNow);
+
+    const lighting = state.lighting;
+    $('#auto-lighting')[0].textContent = lighting === null ? "None" :
+        `${lighting.modes[0]}/${lighting.modes[1]} with ${lighting.palette} palette (${lighting.character})`;
+
+    const animations = state.animations;
+    $('#auto-animations')[0].textContent = animations === null ? "None" :
+        animations.animations.map(a => `${a.name || a.id} on ${a.displays.join(", ")}`).join("; ") +
+        ` (${animations.character})`;
+}
+
+function describeSwitch(beatsLeft, deadline, boringInSec, secondsFromNow) {
+    const parts = [];
+    if (beatsLeft > 0) {
+        parts.push(`${beatsLeft} beats left`);
+    } else {
+        parts.push("on the next aligned beat");
+    }
+    if (deadline !== null) parts.push(`calms down in ${secondsFromNow(deadline)} s without beats`);
+    if (boringInSec !== null) parts.push(`boring in ${boringInSec.toFixed(1)} s`);
+
+    return parts.join(", ");
+}
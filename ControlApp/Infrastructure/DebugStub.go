@@ -4,25 +4,36 @@ import (
 	"ControlApp/BoxiBus"
 	"ControlApp/Display"
 	"log"
+	"time"
 )
 
 type DebugStub struct {
-	BeatTriggered bool
+	Beats chan time.Time //Beats to simulate, never any if nil
 }
 
 func (manager DebugStub) GetConnectedDisplays() []Display.ServerDisplay {
 	return []Display.ServerDisplay{Display.ServerDisplay(1)}
 }
-func (manager DebugStub) GetBeatState() bool {
-	result := manager.BeatTriggered
-	manager.BeatTriggered = false
-	return result
+func (manager DebugStub) GetBeats() <-chan time.Time {
+	return manager.Beats
+}
+
+func (manager DebugStub) GetConnectionState() BoxiBus.ConnectionState {
+	return BoxiBus.ConnectionState{Device: "DebugStub", Connected: true, ProtocolVersion: 1}
+}
+
+func (manager DebugStub) GetTelemetry() Telemetry {
+	return Telemetry{}
 }
 
 func (manager DebugStub) SendLightingInstruction(block BoxiBus.MessageBlock) {
 	log.Printf("Lighting instruction sent: %+v \n", block)
 }
 
+func (manager DebugStub) SendRenderedLighting(boxi1 BoxiBus.Color, boxi2 BoxiBus.Color) {
+	//Sent for every frame, too often to log
+}
+
 func (manager DebugStub) SendAnimationInstruction(animation Display.AnimationId, displays []Display.ServerDisplay) {
 	log.Printf("Animation instruction sent, animation: %d, displays: %+v \n", animation, displays)
 }
@@ -54,3 +65,7 @@ func (manager DebugStub) UpdateStatusCode(statusCode BoxiBus.DisplayStatusCode,
 func (manager DebugStub) SetAnimationProvider(animationProvider AnimationProvider) {
 
 }
+
+func (manager DebugStub) SetLightingObserver(lightingObserver LightingObserver) {
+
+}
@@ -0,0 +1,42 @@
+package Infrastructure
+
+import (
+	"ControlApp/BeatDetection"
+	"encoding/json"
+	"log"
+	"os"
+)
+
+type HardwareConfiguration struct {
+	SerialDevice string //The UART device the Arduino is connected to
+	BaudRate     int    //The baud rate of the UART connection to the Arduino
+
+	BeatSource BeatDetection.SourceConfiguration //Where the beats come from
+}
+
+const hardwareConfigPath = "Configuration/hardware.json"
+
+// LoadHardwareConfiguration reads the hardware configuration, falling back to the defaults of the Boxi hardware.
+func LoadHardwareConfiguration() HardwareConfiguration {
+	config := HardwareConfiguration{
+		SerialDevice: "/dev/ttyAMA0",
+		BaudRate:     38400,
+		BeatSource:   BeatDetection.SourceConfiguration{Type: BeatDetection.ImpulseInput},
+	}
+
+	configFile, err := os.Open(hardwareConfigPath)
+	if err != nil {
+		log.Printf("Config file for hardware could not be accessed, using defaults. %s", err)
+		return config
+	}
+
+	defer func(configFile *os.File) {
+		_ = configFile.Close()
+	}(configFile)
+
+	if err := json.NewDecoder(configFile).Decode(&config); err != nil {
+		log.Fatalf("Invalid JSON format of hardware config file! %s", err)
+	}
+
+	return config
+}
@@ -3,14 +3,19 @@ package Infrastructure
 import (
 	"ControlApp/BoxiBus"
 	"ControlApp/Display"
+	"time"
 )
 
 type HardwareInterface interface {
 	GetConnectedDisplays() []Display.ServerDisplay
-	GetBeatState() bool
+	GetBeats() <-chan time.Time
+	GetConnectionState() BoxiBus.ConnectionState
+	GetTelemetry() Telemetry
 	SetAnimationProvider(animationProvider AnimationProvider)
+	SetLightingObserver(lightingObserver LightingObserver)
 	UpdateStatusCode(statusCode BoxiBus.DisplayStatusCode, serverId byte)
 	SendLightingInstruction(block BoxiBus.MessageBlock)
+	SendRenderedLighting(boxi1 BoxiBus.Color, boxi2 BoxiBus.Color)
 	SendAnimationInstruction(animation Display.AnimationId, displays []Display.ServerDisplay)
 	SendTextInstruction(text string, displays []Display.ServerDisplay)
 	SendBrightnessChange(brightness *float64, blinkSpeed uint16)
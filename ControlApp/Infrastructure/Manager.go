@@ -1,36 +1,51 @@
 package Infrastructure
 
 import (
+	"ControlApp/BeatDetection"
 	"ControlApp/BoxiBus"
 	"ControlApp/Display"
+	"ControlApp/Dmx"
+	"errors"
 	"fmt"
 	"log"
 	"math"
+	"sync"
 	"time"
-
-	"periph.io/x/conn/v3/gpio"
-	"periph.io/x/host/v3"
-	"periph.io/x/host/v3/rpi"
 )
 
 type Manager struct {
 	displayServers    *Display.ServerManager
 	microController   *BoxiBus.CommunicationHub
+	dmxOutput         *Dmx.Output //Nil if no DMX fixtures are connected
 	brightness        float64
 	blinkSpeed        uint16
 	animationProvider AnimationProvider
-	beatInput         gpio.PinIO
+	lightingObserver  LightingObserver
+	beatSource        BeatDetection.BeatSource
+	telemetry         Telemetry
+	lock              *sync.Mutex //Guards the lighting observer and the telemetry, which are updated by the hub's listeners
+}
+
+// Telemetry is the state last reported by the Arduino. It stays empty for firmware that doesn't send telemetry,
+// like lightshow_v3.
+type Telemetry struct {
+	Reported   bool //Whether the Arduino reported anything since the start
+	StatusCode BoxiBus.DisplayStatusCode
+	Brightness float64   //The master brightness set by the potentiometer at A6, between 0 and 1
+	LastBeat   time.Time //When the Arduino last detected a beat at D7
 }
 
 type AnimationProvider interface {
 	GetAllAnimationIds() []Display.AnimationId
 }
 
-func Initialize() (*Manager, error) {
-	connection, err := BoxiBus.ConnectToArduino(38400)
-	if err != nil {
-		log.Fatal(err)
-	}
+// LightingObserver gets notified when the Arduino reports that a lighting mode took effect.
+type LightingObserver interface {
+	LightingApplied(mode BoxiBus.LightingModeId)
+}
+
+func Initialize(config HardwareConfiguration, fixtureConfig Dmx.FixtureConfiguration) (*Manager, error) {
+	connection := BoxiBus.ConnectToArduino(config.SerialDevice, config.BaudRate)
 
 	displays, err := Display.ListenForServers(true)
 	if err != nil {
@@ -40,20 +55,32 @@ func Initialize() (*Manager, error) {
 		return &Manager{}, err
 	}
 
-	pin, err := initBeatPin()
+	beatSource, err := BeatDetection.CreateBeatSource(config.BeatSource)
 	if err != nil {
-		return &Manager{}, fmt.Errorf("GPIOs could not be initialized: %s", err)
+		return &Manager{}, fmt.Errorf("beat source could not be opened: %s", err)
+	}
+
+	var dmxOutput *Dmx.Output
+	if len(fixtureConfig.Outputs) > 0 {
+		dmxOutput, err = Dmx.StartOutput(fixtureConfig)
+		if err != nil {
+			return &Manager{}, fmt.Errorf("DMX output could not be started: %s", err)
+		}
 	}
 
 	manager := &Manager{
 		displayServers:    displays,
 		microController:   connection,
+		dmxOutput:         dmxOutput,
 		animationProvider: nil,
-		beatInput:         pin,
+		beatSource:        beatSource,
 		brightness:        1,
+		lock:              &sync.Mutex{},
 	}
 
 	go manager.handleDisplayServerLogon(displays.ServerConnected)
+	go manager.handleLightingAcknowledgements(connection.Acknowledgements)
+	go manager.handleTelemetry(connection)
 
 	return manager, nil
 }
@@ -62,21 +89,11 @@ func (manager *Manager) SetAnimationProvider(animationProvider AnimationProvider
 	manager.animationProvider = animationProvider
 }
 
-func initBeatPin() (gpio.PinIO, error) {
-	// Initialize periph.io
-	if _, err := host.Init(); err != nil {
-		return nil, fmt.Errorf("failed to initialize periph: %s", err)
-	}
-
-	// Use rpi.P1_11 (GPIO56 on physical pin 19)
-	pin := rpi.P1_16
-
-	// Set as input
-	if err := pin.In(gpio.PullDown, gpio.NoEdge); err != nil {
-		return nil, fmt.Errorf("failed to set pin as input: %s", err)
-	}
+func (manager *Manager) SetLightingObserver(lightingObserver LightingObserver) {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
 
-	return pin, nil
+	manager.lightingObserver = lightingObserver
 }
 
 // handleDisplayServerLogon reports the logon of a display server to the µCs.
@@ -92,7 +109,7 @@ func (manager *Manager) handleDisplayServerLogon(logonChannel <-chan byte) {
 		// Send status update to Arduino
 		message := BoxiBus.CreateDisplayStatusUpdate(BoxiBus.HostAwake, serverId)
 		err := manager.microController.Send(message)
-		if err != nil {
+		if err != nil && !errors.Is(err, BoxiBus.ErrNotConnected) {
 			log.Print(err)
 		}
 
@@ -108,13 +125,63 @@ func (manager *Manager) handleDisplayServerLogon(logonChannel <-chan byte) {
 	}
 }
 
+// handleLightingAcknowledgements forwards the lighting modes applied by the Arduino to the observer.
+func (manager *Manager) handleLightingAcknowledgements(acknowledgements <-chan BoxiBus.LightingModeId) {
+	for mode := range acknowledgements {
+		manager.lock.Lock()
+		lightingObserver := manager.lightingObserver
+		manager.lock.Unlock()
+
+		if lightingObserver != nil {
+			lightingObserver.LightingApplied(mode)
+		}
+	}
+}
+
+// handleTelemetry keeps the status code, brightness and beats reported by the Arduino.
+func (manager *Manager) handleTelemetry(connection *BoxiBus.CommunicationHub) {
+	for {
+		select {
+		case statusCode := <-connection.StatusCodes:
+			manager.updateTelemetry(func(telemetry *Telemetry) { telemetry.StatusCode = statusCode })
+		case brightness := <-connection.Brightness:
+			manager.updateTelemetry(func(telemetry *Telemetry) { telemetry.Brightness = brightness })
+		case beat := <-connection.Beats:
+			manager.updateTelemetry(func(telemetry *Telemetry) { telemetry.LastBeat = beat })
+		}
+	}
+}
+
+func (manager *Manager) updateTelemetry(change func(telemetry *Telemetry)) {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	change(&manager.telemetry)
+	manager.telemetry.Reported = true
+}
+
+// GetTelemetry returns the state last reported by the Arduino.
+func (manager *Manager) GetTelemetry() Telemetry {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	return manager.telemetry
+}
+
 func (manager *Manager) SendLightingInstruction(block BoxiBus.MessageBlock) {
+	//While disconnected, the hub restores the lighting itself once it is back
 	err := manager.microController.Send(block)
-	if err != nil {
+	if err != nil && !errors.Is(err, BoxiBus.ErrNotConnected) {
 		log.Printf("Error sending lighting instruction: %s", err)
 	}
 }
 
+func (manager *Manager) SendRenderedLighting(boxi1 BoxiBus.Color, boxi2 BoxiBus.Color) {
+	if manager.dmxOutput != nil {
+		manager.dmxOutput.SetColors(boxi1, boxi2)
+	}
+}
+
 func (manager *Manager) SendAnimationInstruction(animation Display.AnimationId, displays []Display.ServerDisplay) {
 	totalDisplay := 0
 	for _, display := range displays {
@@ -148,7 +215,7 @@ func (manager *Manager) SendBrightnessChange(brightness *float64, blinkSpeed uin
 
 func (manager *Manager) SendInternalLedConfig(enable bool) {
 	err := manager.microController.Send(BoxiBus.CreateConfigInternalLeds(enable))
-	if err != nil {
+	if err != nil && !errors.Is(err, BoxiBus.ErrNotConnected) {
 		log.Printf("Error sending lighting instruction: %s", err)
 	}
 }
@@ -160,8 +227,8 @@ func (manager *Manager) SendBeatToDisplay(force bool) {
 	manager.displayServers.SetBrightness(manager.brightness, manager.blinkSpeed)
 }
 
-func (manager *Manager) GetBeatState() bool {
-	return manager.beatInput.Read() == gpio.High
+func (manager *Manager) GetBeats() <-chan time.Time {
+	return manager.beatSource.Beats()
 }
 
 func (manager *Manager) GetConnectedDisplays() []Display.ServerDisplay {
@@ -183,3 +250,7 @@ func (manager *Manager) UpdateStatusCode(statusCode BoxiBus.DisplayStatusCode, s
 	message := BoxiBus.CreateDisplayStatusUpdate(statusCode, serverId)
 	_ = manager.microController.Send(message)
 }
+
+func (manager *Manager) GetConnectionState() BoxiBus.ConnectionState {
+	return manager.microController.GetConnectionState()
+}
@@ -17,6 +17,16 @@ type Animation struct {
 	Mood               LightingMood
 	IsNsfw             bool
 	SecondaryAnimation Display.AnimationId
+	Weight             float64 //Relative chance of being picked by auto mode, 0 for the default weight
+}
+
+func (animation Animation) selectionId() uint32 {
+	return uint32(animation.Id)
+}
+
+// GetWeight returns the relative chance of the animation being picked by auto mode.
+func (animation Animation) GetWeight() float64 {
+	return getWeight(animation.Weight)
 }
 
 type AnimationManager struct {
@@ -29,9 +39,9 @@ const animationsConfigPath = "Configuration/animations.json"
 const animationsConfigBackupPath = "Configuration/animations_backup.json"
 
 func LoadAnimations() *AnimationManager {
-	config, err := loadConfiguration[map[Display.AnimationId]Animation](animationsConfigPath)
+	config, err := LoadConfiguration[map[Display.AnimationId]Animation](animationsConfigPath)
 	if err != nil {
-		config, err = loadConfiguration[map[Display.AnimationId]Animation](animationsConfigBackupPath)
+		config, err = LoadConfiguration[map[Display.AnimationId]Animation](animationsConfigBackupPath)
 	}
 
 	if err != nil {
@@ -52,7 +62,7 @@ func LoadAnimations() *AnimationManager {
 }
 
 func (manager *AnimationManager) storeConfiguration() {
-	storeConfiguration(&manager.animations, animationsConfigPath, animationsConfigBackupPath)
+	StoreConfiguration(&manager.animations, animationsConfigPath, animationsConfigBackupPath)
 }
 
 func (manager *AnimationManager) ImportAnimation(animationPath string, name string, mood LightingMood, splitAnimation bool, nsfw bool) (Display.AnimationId, error) {
@@ -67,7 +77,7 @@ func (manager *AnimationManager) ImportAnimation(animationPath string, name stri
 			return 0, err
 		}
 
-		animation := Animation{Display.AnimationId(animationId), name, mood, nsfw, Display.None}
+		animation := Animation{Display.AnimationId(animationId), name, mood, nsfw, Display.None, DefaultWeight}
 		manager.animations[animation.Id] = animation
 		manager.storeConfiguration()
 		manager.UploadQueue <- animation.Id
@@ -83,7 +93,7 @@ func (manager *AnimationManager) ImportAnimation(animationPath string, name stri
 	}
 
 	rightAnimationId := Display.AnimationId(secondaryAnimationId)
-	animation := Animation{Display.AnimationId(animationId), name, mood, nsfw, rightAnimationId}
+	animation := Animation{Display.AnimationId(animationId), name, mood, nsfw, rightAnimationId, DefaultWeight}
 	manager.animations[animation.Id] = animation
 	manager.storeConfiguration()
 	manager.UploadQueue <- animation.Id
@@ -148,6 +158,25 @@ func (manager *AnimationManager) GetAllAnimationIds() []Display.AnimationId {
 	return animations
 }
 
+func (manager *AnimationManager) SetWeight(animationId Display.AnimationId, weight float64) error {
+	if !ValidateWeight(weight) {
+		return fmt.Errorf("the weight of an animation must be between 0 and %g", MaxWeight)
+	}
+
+	manager.accessLock.Lock()
+	defer manager.accessLock.Unlock()
+
+	animation, ok := manager.animations[animationId]
+	if !ok {
+		return fmt.Errorf("animation %d does not exist", animationId)
+	}
+
+	animation.Weight = weight
+	manager.animations[animationId] = animation
+	manager.storeConfiguration()
+	return nil
+}
+
 func (manager *AnimationManager) RemoveAnimation(animationId Display.AnimationId) {
 	manager.accessLock.Lock()
 	defer manager.accessLock.Unlock()
@@ -1,7 +1,11 @@
 package Lightshow
 
 import (
+	"ControlApp/BeatDetection"
+	"ControlApp/BoxiBus"
+	"context"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -9,14 +13,32 @@ type Manager interface {
 	applyLighting(instruction LightingInstruction)
 	applyAnimation(instruction AnimationsInstruction)
 	triggerBeat()
-	getBeatState() bool
+	getBeats() <-chan time.Time
+	SupportsLightingMode(mode BoxiBus.LightingModeId) bool
+	SupportsPerBoxiLighting() bool
 	GetAnimations() *AnimationManager
 	GetPalettes() *PaletteManager
 }
 
+// Clock tells auto mode the time, so it can run on simulated time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (clock systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// AutoModeContext picks lighting and animations following the beats of the music. All state is guarded
+// by the lock, as it is read and changed while auto mode runs.
 type AutoModeContext struct {
 	Configuration         AutoModeConfiguration
 	manager               Manager
+	clock                 Clock
+	random                *rand.Rand
+	tempo                 *BeatDetection.TempoTracker
 	lastBeat              *time.Time
 	lightingSwitchToCalm  *time.Time
 	animationSwitchToCalm *time.Time
@@ -24,147 +46,274 @@ type AutoModeContext struct {
 	animationDeadTime     *time.Duration
 	lightingBeatsLeft     int
 	animationBeatsLeft    int
+	beats                 BeatCounter
+	lightingBoundary      int //Beats of the sections lighting switches align to
+	animationBoundary     int //Beats of the sections animation switches align to
+	paletteHistory        *selectionHistory
+	animationHistory      *selectionHistory
+	lighting              *AutoModeLighting      //The last lighting picked, nil if none was yet
+	animations            *AnimationsInstruction //The last animations picked, nil if none were yet
 	wasInCalmMode         bool
 	isDirty               bool
+	isPaused              bool
+	lock                  *sync.Mutex
+	dirty                 chan struct{}      //Wakes up auto mode when the lightshow is marked dirty
+	wake                  chan struct{}      //Wakes up auto mode when it is resumed
+	stop                  context.CancelFunc //Stops the running auto mode, nil if it isn't running
+	stopped               chan struct{}      //Closed once the running auto mode stopped
 }
 
-const loopDelayMs = 5
-
+// CreateAutoMode sets up auto mode, which picks lighting and animations once it is started.
 func CreateAutoMode(switcher Manager, configuration AutoModeConfiguration) *AutoModeContext {
-	lightingSwitchTime := time.Now().Add(configuration.LightingCalmModeBoring)
-	animationSwitchTime := time.Now().Add(configuration.AnimationCalmModeBoring)
-	result := &AutoModeContext{
+	return createAutoModeContext(switcher, configuration, systemClock{}, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// createAutoModeContext sets up auto mode without running it. All decisions are taken with the given
+// clock and random source.
+func createAutoModeContext(switcher Manager, configuration AutoModeConfiguration, clock Clock, random *rand.Rand) *AutoModeContext {
+	lightingSwitchTime := clock.Now().Add(configuration.LightingCalmModeBoring)
+	animationSwitchTime := clock.Now().Add(configuration.AnimationCalmModeBoring)
+	return &AutoModeContext{
 		Configuration:         configuration,
 		manager:               switcher,
+		clock:                 clock,
+		random:                random,
+		tempo:                 BeatDetection.CreateTempoTracker(),
+		paletteHistory:        createSelectionHistory(paletteRepeatWindow),
+		animationHistory:      createSelectionHistory(animationRepeatWindow),
 		lightingSwitchToCalm:  &lightingSwitchTime,
 		animationSwitchToCalm: &animationSwitchTime,
+		lock:                  &sync.Mutex{},
+		dirty:                 make(chan struct{}, 1),
+		wake:                  make(chan struct{}, 1),
 	}
-
-	go result.calculateAutoMode()
-	return result
 }
 
-func (context *AutoModeContext) calculateAutoMode() {
+// markDirty makes auto mode pick new lighting and animations right away.
+func (context *AutoModeContext) markDirty() {
+	select {
+	case context.dirty <- struct{}{}:
+	default:
+	}
+}
 
-	for {
-		time.Sleep(loopDelayMs * time.Millisecond)
+func (context *AutoModeContext) calculateAutoMode(ctx context.Context, stopped chan<- struct{}) {
+	defer close(stopped)
 
-		//Only count beat if we're not in an exclusively calm mood
-		pendingBeat := context.manager.getBeatState() && !context.Configuration.Mood.IsCalm()
-		now := time.Now()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	beats := context.manager.getBeats()
 
-		var lastBeat time.Time
-		if context.lastBeat == nil {
-			lastBeat = now
+	for {
+		//Sleep until a beat arrives or something is due
+		if deadline, ok := context.getNextDeadline(); ok {
+			timer.Reset(deadline.Sub(context.clock.Now()))
 		} else {
-			lastBeat = *context.lastBeat
+			timer.Stop()
 		}
 
-		isBeat := pendingBeat && (context.lastBeat == nil || now.After(context.lastBeat.Add(context.Configuration.MinTimeBetweenBeats)))
-		if isBeat || (!context.wasInCalmMode && context.isDirty && !context.Configuration.Mood.IsCalm()) {
-			context.lastBeat = &now
-			context.manager.triggerBeat()
-			context.lightingSwitchToCalm = nil
-			context.animationSwitchToCalm = nil
-
-			// Count down the display beat timer and play new animation if limit was reached
-			context.animationBeatsLeft--
-			if context.animationBeatsLeft <= 0 {
-				var animation AnimationsInstruction
-				if context.isDirty {
-					animation = context.getNextAnimation(FirstBeat)
-				} else {
-					animation = context.getNextAnimation(OnBeat)
-				}
-				context.manager.applyAnimation(animation)
-
-				timingConstraint, ok := context.Configuration.AnimationModeTiming[animation.Character]
-				if ok {
-					context.animationBeatsLeft = getNextBeatConstraint(timingConstraint)
-					context.animationDeadTime = &timingConstraint.NoBeatDeadTime
-				}
-			}
+		select {
+		case <-ctx.Done():
+			return
+		case at := <-beats:
+			context.handle(&at, false)
+		case <-context.dirty:
+			context.handle(nil, true)
+		case <-context.wake:
+			context.handle(nil, false)
+		case <-timer.C:
+			context.handle(nil, false)
+		}
+	}
+}
 
-			// Count down the animation beat timer and play new animation if limit was reached
-			context.lightingBeatsLeft--
-			if context.lightingBeatsLeft <= 0 {
-				var lighting LightingInstruction
-				if context.wasInCalmMode {
-					lighting = context.getNextLighting(FirstBeat)
-				} else {
-					lighting = context.getNextLighting(OnBeat)
-				}
-
-				context.manager.applyLighting(lighting)
-
-				timingConstraint, ok := context.Configuration.LightingModeTiming[lighting.character]
-				if ok {
-					context.lightingBeatsLeft = getNextBeatConstraint(timingConstraint)
-					context.lightingDeadTime = &timingConstraint.NoBeatDeadTime
-				}
-			}
+// handle passes a beat detected at the given time, nil if there is none, or a change to the state machine.
+// Double triggers are dropped and missed beats filled in once the tempo is known. While paused, only
+// the tempo is tracked.
+func (context *AutoModeContext) handle(beat *time.Time, isDirty bool) {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	var detectedBeat bool
+	if beat != nil {
+		detectedBeat = context.tempo.AddBeat(*beat)
+	} else if !context.isPaused {
+		detectedBeat = context.tempo.Update(context.clock.Now())
+	}
+
+	context.isDirty = context.isDirty || isDirty
+	if context.isPaused {
+		return
+	}
+
+	context.update(detectedBeat)
+}
+
+// update runs the state machine after a beat, a change or when something is due. Requires the lock.
+func (context *AutoModeContext) update(detectedBeat bool) {
+	now := context.clock.Now()
+
+	//Only count beat if we're not in an exclusively calm mood
+	pendingBeat := detectedBeat && !context.Configuration.Mood.IsCalm()
 
-			context.wasInCalmMode = false
-			context.isDirty = false
-			continue
+	var lastBeat time.Time
+	if context.lastBeat == nil {
+		lastBeat = now
+	} else {
+		lastBeat = *context.lastBeat
+	}
+
+	isBeat := pendingBeat && (context.lastBeat == nil || now.After(context.lastBeat.Add(context.Configuration.MinTimeBetweenBeats)))
+	if isBeat || (!context.wasInCalmMode && context.isDirty && !context.Configuration.Mood.IsCalm()) {
+		if isBeat {
+			context.beats.Count(context.lastBeat == nil || context.wasInCalmMode)
 		}
 
-		//Check beat dead time for animation
-		if context.animationDeadTime != nil && lastBeat.Add(*context.animationDeadTime).Before(time.Now()) {
-			context.animationDeadTime = nil
-			context.animationBeatsLeft = 0
-			animation := context.getNextAnimation(InDeadTime)
+		context.lastBeat = &now
+		context.manager.triggerBeat()
+		context.lightingSwitchToCalm = nil
+		context.animationSwitchToCalm = nil
+
+		// Count down the display beat timer and play new animation if limit was reached
+		context.animationBeatsLeft--
+		if context.animationBeatsLeft <= 0 && (context.isDirty || context.beats.IsBoundary(context.animationBoundary)) {
+			var animation AnimationsInstruction
+			if context.isDirty {
+				animation = context.getNextAnimation(FirstBeat)
+			} else {
+				animation = context.getNextAnimation(OnBeat)
+			}
 			context.manager.applyAnimation(animation)
 
-			if animation.Character == Calm {
-				timeWhenBoring := time.Now().Add(context.Configuration.AnimationCalmModeBoring)
-				context.animationSwitchToCalm = &timeWhenBoring
+			timingConstraint, ok := context.Configuration.AnimationModeTiming[animation.Character]
+			if ok {
+				context.animationBeatsLeft = context.getNextBeatConstraint(timingConstraint)
+				context.animationDeadTime = &timingConstraint.NoBeatDeadTime
+				context.animationBoundary = context.Configuration.getSwitchBoundary(timingConstraint)
 			}
 		}
 
-		//Check beat dead time for lighting
-		if context.lightingDeadTime != nil && lastBeat.Add(*context.lightingDeadTime).Before(time.Now()) {
-			context.lightingDeadTime = nil
-			context.lightingBeatsLeft = 0
-			lighting := context.getNextLighting(InDeadTime)
+		// Count down the animation beat timer and play new animation if limit was reached
+		context.lightingBeatsLeft--
+		if context.lightingBeatsLeft <= 0 && (context.isDirty || context.beats.IsBoundary(context.lightingBoundary)) {
+			var lighting LightingInstruction
+			if context.wasInCalmMode {
+				lighting = context.getNextLighting(FirstBeat)
+			} else {
+				lighting = context.getNextLighting(OnBeat)
+			}
+
 			context.manager.applyLighting(lighting)
-			context.wasInCalmMode = lighting.character == Calm
 
-			if context.wasInCalmMode {
-				timeWhenBoring := time.Now().Add(context.Configuration.LightingCalmModeBoring)
-				context.lightingSwitchToCalm = &timeWhenBoring
+			timingConstraint, ok := context.Configuration.LightingModeTiming[lighting.character]
+			if ok {
+				context.lightingBeatsLeft = context.getNextBeatConstraint(timingConstraint)
+				context.lightingDeadTime = &timingConstraint.NoBeatDeadTime
+				context.lightingBoundary = context.Configuration.getSwitchBoundary(timingConstraint)
 			}
 		}
 
-		//Check if the calm animation is boring
-		if context.animationSwitchToCalm != nil && context.animationSwitchToCalm.Before(time.Now()) || context.isDirty {
-			context.animationSwitchToCalm = nil
-			animation := context.getNextAnimation(InCalmMode)
-			context.manager.applyAnimation(animation)
+		context.wasInCalmMode = false
+		context.isDirty = false
+		return
+	}
 
-			if animation.Character == Calm {
-				timeWhenBoring := time.Now().Add(context.Configuration.AnimationCalmModeBoring)
-				context.animationSwitchToCalm = &timeWhenBoring
-			}
+	//Check beat dead time for animation
+	if context.animationDeadTime != nil && lastBeat.Add(*context.animationDeadTime).Before(now) {
+		context.animationDeadTime = nil
+		context.animationBeatsLeft = 0
+		animation := context.getNextAnimation(InDeadTime)
+		context.manager.applyAnimation(animation)
+
+		if animation.Character == Calm {
+			timeWhenBoring := now.Add(context.Configuration.AnimationCalmModeBoring)
+			context.animationSwitchToCalm = &timeWhenBoring
 		}
+	}
 
-		//Check if the calm lighting is boring
-		if context.lightingSwitchToCalm != nil && context.lightingSwitchToCalm.Before(time.Now()) || context.isDirty {
-			context.lightingSwitchToCalm = nil
-			lighting := context.getNextLighting(InCalmMode)
-			context.manager.applyLighting(lighting)
-			context.wasInCalmMode = true
+	//Check beat dead time for lighting
+	if context.lightingDeadTime != nil && lastBeat.Add(*context.lightingDeadTime).Before(now) {
+		context.lightingDeadTime = nil
+		context.lightingBeatsLeft = 0
+		lighting := context.getNextLighting(InDeadTime)
+		context.manager.applyLighting(lighting)
+		context.wasInCalmMode = lighting.character == Calm
 
-			if lighting.character == Calm {
-				timeWhenBoring := time.Now().Add(context.Configuration.LightingCalmModeBoring)
-				context.lightingSwitchToCalm = &timeWhenBoring
-			}
+		if context.wasInCalmMode {
+			timeWhenBoring := now.Add(context.Configuration.LightingCalmModeBoring)
+			context.lightingSwitchToCalm = &timeWhenBoring
 		}
+	}
 
-		context.isDirty = false
+	//Check if the calm animation is boring
+	if context.animationSwitchToCalm != nil && context.animationSwitchToCalm.Before(now) || context.isDirty {
+		context.animationSwitchToCalm = nil
+		animation := context.getNextAnimation(InCalmMode)
+		context.manager.applyAnimation(animation)
+
+		if animation.Character == Calm {
+			timeWhenBoring := now.Add(context.Configuration.AnimationCalmModeBoring)
+			context.animationSwitchToCalm = &timeWhenBoring
+		}
+	}
+
+	//Check if the calm lighting is boring
+	if context.lightingSwitchToCalm != nil && context.lightingSwitchToCalm.Before(now) || context.isDirty {
+		context.lightingSwitchToCalm = nil
+		lighting := context.getNextLighting(InCalmMode)
+		context.manager.applyLighting(lighting)
+		context.wasInCalmMode = true
+
+		if lighting.character == Calm {
+			timeWhenBoring := now.Add(context.Configuration.LightingCalmModeBoring)
+			context.lightingSwitchToCalm = &timeWhenBoring
+		}
 	}
+
+	context.isDirty = false
+}
+
+// getNextDeadline returns when something is due without a beat. The second value is false if nothing is.
+func (context *AutoModeContext) getNextDeadline() (time.Time, bool) {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	if context.isPaused {
+		return time.Time{}, false
+	}
+
+	var deadlines []time.Time
+	if context.lastBeat != nil && context.animationDeadTime != nil {
+		deadlines = append(deadlines, context.lastBeat.Add(*context.animationDeadTime))
+	}
+	if context.lastBeat != nil && context.lightingDeadTime != nil {
+		deadlines = append(deadlines, context.lastBeat.Add(*context.lightingDeadTime))
+	}
+	if context.animationSwitchToCalm != nil {
+		deadlines = append(deadlines, *context.animationSwitchToCalm)
+	}
+	if context.lightingSwitchToCalm != nil {
+		deadlines = append(deadlines, *context.lightingSwitchToCalm)
+	}
+	if fillDeadline, ok := context.tempo.FillDeadline(); ok {
+		deadlines = append(deadlines, fillDeadline)
+	}
+
+	if len(deadlines) == 0 {
+		return time.Time{}, false
+	}
+
+	next := deadlines[0]
+	for _, deadline := range deadlines[1:] {
+		if deadline.Before(next) {
+			next = deadline
+		}
+	}
+
+	//Just after the deadline, the checks expect it to have passed
+	return next.Add(time.Millisecond), true
 }
 
-func getNextBeatConstraint(constraint TimingConstraint) int {
-	return rand.Intn(constraint.MaxNumberOfBeats-constraint.MinNumberOfBeats) + constraint.MinNumberOfBeats
+func (context *AutoModeContext) getNextBeatConstraint(constraint TimingConstraint) int {
+	return context.random.Intn(constraint.MaxNumberOfBeats-constraint.MinNumberOfBeats) + constraint.MinNumberOfBeats
 }
@@ -0,0 +1,200 @@
+package Lightshow
+
+import (
+	"ControlApp/BoxiBus"
+	"context"
+	"errors"
+	"time"
+)
+
+type AutoModePhase uint8
+
+const (
+	WaitingForBeats AutoModePhase = iota //No beat was detected since auto mode started
+	FollowingBeats                       //Switching with the beats of the music
+	CalmedDown                           //The music stopped, switching whenever the calm mode gets boring
+)
+
+func (phase AutoModePhase) String() string {
+	switch phase {
+	case WaitingForBeats:
+		return "WaitingForBeats"
+	case FollowingBeats:
+		return "FollowingBeats"
+	case CalmedDown:
+		return "CalmedDown"
+	}
+
+	return "Unknown"
+}
+
+// AutoModeLighting describes the lighting auto mode picked.
+type AutoModeLighting struct {
+	Palette   string
+	Modes     [2]BoxiBus.LightingModeId //Of Boxi 1 and Boxi 2
+	Character ModeCharacter
+}
+
+// AutoModeStatus is a snapshot of the state of auto mode.
+type AutoModeStatus struct {
+	Running            bool
+	Paused             bool
+	Phase              AutoModePhase
+	WasInCalmMode      bool                   //Whether the lighting calmed down since the last beat
+	LastBeat           *time.Time             //Nil if no beat was detected yet
+	LightingBeatsLeft  int                    //Beats until the lighting switches, it waits for the next boundary if not positive
+	AnimationBeatsLeft int                    //Beats until the animations switch, they wait for the next boundary if not positive
+	LightingDeadline   *time.Time             //When the lighting calms down if no beat comes, nil if it doesn't
+	AnimationDeadline  *time.Time             //When the animations calm down if no beat comes, nil if they don't
+	LightingBoringIn   *time.Duration         //Time until the calm lighting gets boring, nil if it isn't calm
+	AnimationBoringIn  *time.Duration         //Time until the calm animations get boring, nil if they aren't calm
+	Lighting           *AutoModeLighting      //The last lighting picked, nil if none was yet
+	Animations         *AnimationsInstruction //The last animations picked, nil if none were yet
+}
+
+var ErrAutoModeRunning = errors.New("auto mode is already running")
+
+// Start runs auto mode until the context is cancelled or it is stopped.
+func (context *AutoModeContext) Start(ctx context.Context) error {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	if context.stop != nil {
+		return ErrAutoModeRunning
+	}
+
+	runContext, stop := withCancel(ctx)
+	stopped := make(chan struct{})
+	context.stop = stop
+	context.stopped = stopped
+
+	go func() {
+		context.calculateAutoMode(runContext, stopped)
+
+		//Allow restarting when the context was cancelled from outside
+		context.clearRun(stopped)
+	}()
+
+	return nil
+}
+
+// withCancel is context.WithCancel, which can't be reached from methods whose receiver is named context.
+func withCancel(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithCancel(parent)
+}
+
+// Stop stops auto mode and waits until it doesn't touch the lighting and animations anymore.
+func (context *AutoModeContext) Stop() {
+	context.lock.Lock()
+	stop := context.stop
+	stopped := context.stopped
+	context.lock.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	stop()
+	<-stopped
+	context.clearRun(stopped)
+}
+
+// clearRun forgets the run that stopped, unless another one was started since.
+func (context *AutoModeContext) clearRun(stopped chan struct{}) {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	if context.stopped == stopped {
+		context.stop = nil
+		context.stopped = nil
+	}
+}
+
+// Pause keeps the current lighting and animations until auto mode is resumed. The tempo is still tracked.
+func (context *AutoModeContext) Pause() {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	context.isPaused = true
+}
+
+// Resume continues auto mode after a pause. Anything that got due in the meantime happens right away.
+func (context *AutoModeContext) Resume() {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	context.isPaused = false
+
+	select {
+	case context.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the current state of auto mode.
+func (context *AutoModeContext) Status() AutoModeStatus {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	now := context.clock.Now()
+	status := AutoModeStatus{
+		Running:            context.stop != nil,
+		Paused:             context.isPaused,
+		Phase:              FollowingBeats,
+		WasInCalmMode:      context.wasInCalmMode,
+		LightingBeatsLeft:  context.lightingBeatsLeft,
+		AnimationBeatsLeft: context.animationBeatsLeft,
+	}
+
+	if context.lastBeat != nil {
+		lastBeat := *context.lastBeat
+		status.LastBeat = &lastBeat
+
+		if context.lightingDeadTime != nil {
+			deadline := lastBeat.Add(*context.lightingDeadTime)
+			status.LightingDeadline = &deadline
+		}
+		if context.animationDeadTime != nil {
+			deadline := lastBeat.Add(*context.animationDeadTime)
+			status.AnimationDeadline = &deadline
+		}
+	}
+
+	if context.lastBeat == nil {
+		status.Phase = WaitingForBeats
+	} else if context.wasInCalmMode {
+		status.Phase = CalmedDown
+	}
+
+	if context.lightingSwitchToCalm != nil {
+		boringIn := max(context.lightingSwitchToCalm.Sub(now), 0)
+		status.LightingBoringIn = &boringIn
+	}
+	if context.animationSwitchToCalm != nil {
+		boringIn := max(context.animationSwitchToCalm.Sub(now), 0)
+		status.AnimationBoringIn = &boringIn
+	}
+
+	//The picks are replaced instead of changed, so they can be shared
+	status.Lighting = context.lighting
+	status.Animations = context.animations
+
+	return status
+}
+
+// GetConfiguration returns a copy of the configuration.
+func (context *AutoModeContext) GetConfiguration() AutoModeConfiguration {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	return context.Configuration.Clone()
+}
+
+// UpdateConfiguration changes the configuration and stores it.
+func (context *AutoModeContext) UpdateConfiguration(change func(configuration *AutoModeConfiguration)) {
+	context.lock.Lock()
+	defer context.lock.Unlock()
+
+	change(&context.Configuration)
+	context.Configuration.Store()
+}
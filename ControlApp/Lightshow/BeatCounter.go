@@ -0,0 +1,22 @@
+package Lightshow
+
+// BeatCounter follows the position of the beats within the bars and phrases of the music.
+// The first beat after the music started is taken as the start of a phrase.
+type BeatCounter struct {
+	beat int //Beats since the start of the phrase
+}
+
+// Count advances the counter by a beat. If the music just started, the beat starts a new phrase.
+func (counter *BeatCounter) Count(musicStarted bool) {
+	if musicStarted {
+		counter.beat = 0
+		return
+	}
+
+	counter.beat++
+}
+
+// IsBoundary returns whether the current beat starts a section of the given number of beats.
+func (counter *BeatCounter) IsBoundary(sectionBeats int) bool {
+	return sectionBeats <= 1 || counter.beat%sectionBeats == 0
+}
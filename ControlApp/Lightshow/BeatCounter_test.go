@@ -0,0 +1,50 @@
+package Lightshow
+
+import "testing"
+
+func TestBeatCounterIsBoundary(t *testing.T) {
+	tests := []struct {
+		name         string
+		beats        int //Beats counted after the one the music started with
+		sectionBeats int
+		expected     bool
+	}{
+		{"start of the music", 0, 64, true},
+		{"within the first bar", 3, 4, false},
+		{"start of the second bar", 4, 4, true},
+		{"start of a bar within a phrase", 4, 64, false},
+		{"start of the second phrase", 64, 64, true},
+		{"any beat without alignment", 3, 1, true},
+		{"any beat without sections", 3, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			counter := BeatCounter{}
+			counter.Count(true)
+			for i := 0; i < test.beats; i++ {
+				counter.Count(false)
+			}
+
+			if result := counter.IsBoundary(test.sectionBeats); result != test.expected {
+				t.Errorf("boundary of %d beat sections after %d beats is %t, expected %t", test.sectionBeats, test.beats, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestBeatCounterRestartsWithTheMusic(t *testing.T) {
+	counter := BeatCounter{}
+	counter.Count(true)
+	counter.Count(false)
+	counter.Count(false)
+
+	if counter.IsBoundary(4) {
+		t.Fatal("the third beat starts a bar")
+	}
+
+	counter.Count(true)
+	if !counter.IsBoundary(64) {
+		t.Error("the music starting again doesn't start a phrase")
+	}
+}
@@ -13,9 +13,9 @@ const (
 
 func getLightingModeCharacter(modeId BoxiBus.LightingModeId) ModeCharacter {
 	switch modeId {
-	case BoxiBus.SetColor, BoxiBus.FadeToColor, BoxiBus.PaletteFade:
+	case BoxiBus.SetColor, BoxiBus.FadeToColor, BoxiBus.PaletteFade, BoxiBus.RainbowCycle, BoxiBus.Breathing:
 		return Calm
-	case BoxiBus.PaletteSwitch, BoxiBus.PaletteBrightnessFlash, BoxiBus.PaletteHueFlash:
+	case BoxiBus.PaletteSwitch, BoxiBus.PaletteBrightnessFlash, BoxiBus.PaletteHueFlash, BoxiBus.Chase:
 		return Rhythmic
 	case BoxiBus.Strobe:
 		return Frantic
@@ -23,3 +23,16 @@ func getLightingModeCharacter(modeId BoxiBus.LightingModeId) ModeCharacter {
 
 	return Unknown
 }
+
+func (character ModeCharacter) String() string {
+	switch character {
+	case Calm:
+		return "Calm"
+	case Rhythmic:
+		return "Rhythmic"
+	case Frantic:
+		return "Frantic"
+	}
+
+	return "Unknown"
+}
@@ -8,12 +8,13 @@ import (
 	"os"
 )
 
-func loadConfiguration[N any](path string) (N, error) {
+// LoadConfiguration reads a JSON config file.
+func LoadConfiguration[N any](path string) (N, error) {
 	configFile, err := os.Open(path)
 
 	var config N
 	if err != nil {
-		return config, fmt.Errorf("config file for auto mode could not be accessed, %s", err)
+		return config, fmt.Errorf("config file %s could not be accessed, %s", path, err)
 	}
 
 	defer func(configFile *os.File) {
@@ -24,13 +25,14 @@ func loadConfiguration[N any](path string) (N, error) {
 
 	err = jsonParser.Decode(&config)
 	if err != nil {
-		return config, fmt.Errorf("invalid JSON format of auto mode config file, %s", err)
+		return config, fmt.Errorf("invalid JSON format of config file %s, %s", path, err)
 	}
 
 	return config, nil
 }
 
-func storeConfiguration[N any](config *N, basePath string, backupPath string) {
+// StoreConfiguration writes a JSON config file, keeping the previous one as the backup.
+func StoreConfiguration[N any](config *N, basePath string, backupPath string) {
 	_ = os.Remove(backupPath)
 	err := copyFile(basePath, backupPath)
 	if err != nil {
@@ -41,7 +43,7 @@ func storeConfiguration[N any](config *N, basePath string, backupPath string) {
 	configFile, err := os.OpenFile(basePath, os.O_CREATE|os.O_WRONLY, os.ModePerm)
 
 	if err != nil {
-		log.Fatalf("Config file for auto mode could not be opened for writing! %s", err)
+		log.Fatalf("Config file %s could not be opened for writing! %s", basePath, err)
 	}
 
 	defer func(configFile *os.File) {
@@ -51,7 +53,7 @@ func storeConfiguration[N any](config *N, basePath string, backupPath string) {
 	jsonParser := json.NewEncoder(configFile)
 	err = jsonParser.Encode(config)
 	if err != nil {
-		log.Fatalf("Configuration for auto mode could be JSON encoded! %s", err)
+		log.Fatalf("Configuration for %s could not be JSON encoded! %s", basePath, err)
 	}
 }
 
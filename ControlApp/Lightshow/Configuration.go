@@ -2,28 +2,37 @@ package Lightshow
 
 import (
 	"log"
+	"maps"
 	"time"
 )
 
 type AutoModeConfiguration struct {
-	Mood                    LightingMood
-	AllowNsfw               bool
-	StrobeChance            int
-	HueShiftChance          int
-	HueShiftMaxAmount       int
-	FadeToColorCycles       uint16 //How slow is the “FadeToColor” mode operating at
-	PaletteFadeCycles       uint16 //How slow is the “FadeToColor” mode operating at
-	FlashFadeoutSpeed       uint16
-	HueFlashFadeoutSpeed    uint16
-	StrobeFrequency         uint16
-	StrobeRolloff           byte
-	FlashTargetBrightness   byte
-	FlashHueShift           byte
-	MinTimeBetweenBeats     time.Duration
-	LightingCalmModeBoring  time.Duration                      //How long it takes until a calm animation is boring
-	AnimationCalmModeBoring time.Duration                      //How long it takes until a calm animation is boring
-	LightingModeTiming      map[ModeCharacter]TimingConstraint //The timing constraints for lighting of any character
-	AnimationModeTiming     map[ModeCharacter]TimingConstraint //The timing constraints for animations of any character
+	Mood                      LightingMood
+	AllowNsfw                 bool
+	StrobeChance              int
+	HueShiftChance            int
+	HueShiftMaxAmount         int
+	FadeToColorCycles         uint16 //How slow is the “FadeToColor” mode operating at
+	PaletteFadeCycles         uint16 //How slow is the “FadeToColor” mode operating at
+	FlashFadeoutSpeed         uint16
+	HueFlashFadeoutSpeed      uint16
+	StrobeFrequency           uint16
+	StrobeRolloff             byte
+	FlashTargetBrightness     byte
+	FlashHueShift             byte
+	ChaseBackgroundBrightness byte   //How bright the unlit Boxi is in the “Chase” mode
+	RainbowCycleCycles        uint16 //How slow is the “RainbowCycle” mode operating at
+	BreathingCycles           uint16 //How slow is the “Breathing” mode operating at
+	BreathingMinBrightness    byte   //How dark the “Breathing” mode gets
+	IndependentBoxis          bool   //Whether each Boxi may run its own, complementary lighting mode
+	PhraseAligned             bool   //Whether lighting and animations only switch at the start of a phrase
+	BeatsPerBar               int    //The time signature of the music
+	BarsPerPhrase             int
+	MinTimeBetweenBeats       time.Duration
+	LightingCalmModeBoring    time.Duration                      //How long it takes until a calm animation is boring
+	AnimationCalmModeBoring   time.Duration                      //How long it takes until a calm animation is boring
+	LightingModeTiming        map[ModeCharacter]TimingConstraint //The timing constraints for lighting of any character
+	AnimationModeTiming       map[ModeCharacter]TimingConstraint //The timing constraints for animations of any character
 }
 
 type TimingConstraint struct {
@@ -45,22 +54,59 @@ const autoModeConfigPath = "Configuration/auto_mode.json"
 
 const autoModeConfigBackupPath = "Configuration/auto_mode_backup.json"
 
-func load() AutoModeConfiguration {
-	config, err := loadConfiguration[AutoModeConfiguration](autoModeConfigPath)
+// LoadAutoModeConfiguration reads the auto mode configuration, falling back to the backup.
+func LoadAutoModeConfiguration() AutoModeConfiguration {
+	config, err := LoadConfiguration[AutoModeConfiguration](autoModeConfigPath)
 
 	if err != nil {
-		config, err = loadConfiguration[AutoModeConfiguration](autoModeConfigBackupPath)
+		config, err = LoadConfiguration[AutoModeConfiguration](autoModeConfigBackupPath)
 	}
 
 	if err != nil {
 		log.Fatalf("Config file for auto mode could not be accessed! %s", err)
 	}
 
+	//Fill in the settings of modes added after the config file was written
+	if config.RainbowCycleCycles == 0 {
+		config.RainbowCycleCycles = 1300
+	}
+	if config.BreathingCycles == 0 {
+		config.BreathingCycles = 520
+		config.BreathingMinBrightness = 25
+	}
+	if config.BeatsPerBar == 0 || config.BarsPerPhrase == 0 {
+		config.BeatsPerBar = 4
+		config.BarsPerPhrase = 16
+	}
+
 	return config
 }
 
+// Clone returns a copy of the configuration that shares no maps with it.
+func (config *AutoModeConfiguration) Clone() AutoModeConfiguration {
+	result := *config
+	result.LightingModeTiming = maps.Clone(config.LightingModeTiming)
+	result.AnimationModeTiming = maps.Clone(config.AnimationModeTiming)
+	return result
+}
+
 func (config *AutoModeConfiguration) Store() {
-	storeConfiguration(config, autoModeConfigPath, autoModeConfigBackupPath)
+	StoreConfiguration(config, autoModeConfigPath, autoModeConfigBackupPath)
+}
+
+// getSwitchBoundary returns the number of beats of the sections switches must align to. Phrases are used if
+// they fit into the most beats of the constraint, otherwise bars. Without alignment, switches happen on any beat.
+func (config *AutoModeConfiguration) getSwitchBoundary(constraint TimingConstraint) int {
+	if !config.PhraseAligned {
+		return 1
+	}
+
+	phraseBeats := config.BeatsPerBar * config.BarsPerPhrase
+	if phraseBeats <= constraint.MaxNumberOfBeats {
+		return phraseBeats
+	}
+
+	return config.BeatsPerBar
 }
 
 // IsCalm returns whether the mood has exclusively calm character.
@@ -0,0 +1,29 @@
+package Lightshow
+
+import "testing"
+
+func TestGetSwitchBoundary(t *testing.T) {
+	tests := []struct {
+		name          string
+		phraseAligned bool
+		maxBeats      int
+		expected      int
+	}{
+		{"not aligned", false, 128, 1},
+		{"phrase fits", true, 128, 64},
+		{"phrase fits exactly", true, 64, 64},
+		{"falls back to bars", true, 63, 4},
+		{"falls back to bars for short modes", true, 2, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := AutoModeConfiguration{PhraseAligned: test.phraseAligned, BeatsPerBar: 4, BarsPerPhrase: 16}
+			constraint := TimingConstraint{MinNumberOfBeats: 1, MaxNumberOfBeats: test.maxBeats}
+
+			if result := config.getSwitchBoundary(constraint); result != test.expected {
+				t.Errorf("switches align to %d beats, expected %d", result, test.expected)
+			}
+		})
+	}
+}
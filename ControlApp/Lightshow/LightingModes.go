@@ -0,0 +1,364 @@
+package Lightshow
+
+import (
+	"ControlApp/BoxiBus"
+	"ControlApp/Infrastructure"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// LightingMode is a lighting mode of the Arduino with its parameters in human units.
+// Encode must only be called on modes that passed Validate, it panics otherwise.
+type LightingMode interface {
+	Id() BoxiBus.LightingModeId
+	Validate() error
+	Encode() BoxiBus.MessageBlock
+}
+
+// OffMode turns the lights off.
+type OffMode struct {
+	ApplyOnBeat bool
+}
+
+// SetColorMode shows a static color on each Boxi.
+type SetColorMode struct {
+	Boxi1       BoxiBus.Color
+	Boxi2       BoxiBus.Color
+	ApplyOnBeat bool
+}
+
+// FadeToColorMode fades from the current colors to a color on each Boxi.
+type FadeToColorMode struct {
+	Boxi1       BoxiBus.Color
+	Boxi2       BoxiBus.Color
+	Duration    time.Duration //How long the fade takes
+	ApplyOnBeat bool
+}
+
+// PaletteFadeMode continuously fades through the colors of a palette.
+type PaletteFadeMode struct {
+	Palette     []BoxiBus.Color
+	Duration    time.Duration //How long the fade from one color to the next takes
+	Shift       int           //How many colors Boxi 2 is ahead of Boxi 1
+	ApplyOnBeat bool
+}
+
+// PaletteSwitchMode switches to the next color of a palette on every beat.
+type PaletteSwitchMode struct {
+	Palette     []BoxiBus.Color
+	Shift       int //How many colors Boxi 2 is ahead of Boxi 1
+	ApplyOnBeat bool
+}
+
+// BrightnessFlashMode flashes the next color of a palette on every beat.
+type BrightnessFlashMode struct {
+	Palette          []BoxiBus.Color
+	FadeOutSpeed     uint16 //How fast the flash fades out, in percent per cycle
+	TargetBrightness int    //The brightness the flash fades out to, in percent
+	Shift            int    //How many colors Boxi 2 is ahead of Boxi 1
+	ApplyOnBeat      bool
+}
+
+// HueFlashMode flashes from one color of a palette to another on every beat.
+type HueFlashMode struct {
+	Palette      []BoxiBus.Color
+	FadeOutSpeed uint16 //How fast the flash fades out, in percent per cycle
+	Shift        int    //How many colors the color faded to is ahead of the flashed color
+	ApplyOnBeat  bool
+}
+
+// StrobeMode strobes a single color.
+type StrobeMode struct {
+	Color       BoxiBus.Color
+	Frequency   float64 //The strobe frequency in Hz
+	Rolloff     byte    //Passed to the general purpose field as is, the lightshow_v3 firmware doesn't use it
+	ApplyOnBeat bool
+}
+
+// ChaseMode lights up the Boxis alternately on every beat, advancing through the palette.
+type ChaseMode struct {
+	Palette              []BoxiBus.Color
+	BackgroundBrightness int //The brightness of the unlit Boxi, in percent
+	ApplyOnBeat          bool
+}
+
+// RainbowCycleMode continuously cycles through all hues.
+type RainbowCycleMode struct {
+	Duration    time.Duration //How long one rotation through all hues takes
+	HueOffset   int           //How many degrees Boxi 2 is ahead of Boxi 1
+	Saturation  int           //The saturation of the colors, in percent
+	ApplyOnBeat bool
+}
+
+// BreathingMode slowly pulses the brightness of the palette colors, switching colors at the darkest point.
+type BreathingMode struct {
+	Palette       []BoxiBus.Color
+	Duration      time.Duration //How long one breath takes
+	MinBrightness int           //The brightness at the darkest point, in percent
+	Shift         int           //How many colors Boxi 2 is ahead of Boxi 1
+	ApplyOnBeat   bool
+}
+
+// FadeDurationToCycles converts a fade duration into the cycles of the Arduino's render loop.
+func FadeDurationToCycles(duration time.Duration) int {
+	return int(math.Round(float64(duration.Milliseconds()) * Infrastructure.FadeDurationMsToCycles))
+}
+
+// CyclesToFadeDuration converts cycles of the Arduino's render loop into a fade duration.
+func CyclesToFadeDuration(cycles uint16) time.Duration {
+	return time.Duration(math.Round(float64(cycles)/Infrastructure.FadeDurationMsToCycles)) * time.Millisecond
+}
+
+// StrobeFrequencyToSpeed converts a strobe frequency in Hz into the speed value of the strobe mode.
+func StrobeFrequencyToSpeed(frequency float64) int {
+	if frequency <= 0 {
+		return 0
+	}
+
+	return int(math.Round(Infrastructure.StrobeFrequencyMultiplier / frequency))
+}
+
+// StrobeSpeedToFrequency converts the speed value of the strobe mode into a frequency in Hz.
+func StrobeSpeedToFrequency(speed uint16) float64 {
+	if speed == 0 {
+		return 0
+	}
+
+	return Infrastructure.StrobeFrequencyMultiplier / float64(speed)
+}
+
+// PercentToByte converts a percentage into the range of a byte.
+func PercentToByte(percent int) byte {
+	return byte(math.Round(float64(min(max(percent, 0), 100)) / 100 * 255))
+}
+
+// ByteToPercent converts a byte into a percentage.
+func ByteToPercent(value byte) int {
+	return int(math.Round(float64(value) / 255 * 100))
+}
+
+func (mode OffMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.Off
+}
+
+func (mode OffMode) Validate() error {
+	return nil
+}
+
+func (mode OffMode) Encode() BoxiBus.MessageBlock {
+	return BoxiBus.CreateLightingOff(mode.ApplyOnBeat)
+}
+
+func (mode SetColorMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.SetColor
+}
+
+func (mode SetColorMode) Validate() error {
+	return nil
+}
+
+func (mode SetColorMode) Encode() BoxiBus.MessageBlock {
+	return BoxiBus.CreateLightingSetColor(mode.Boxi1, mode.Boxi2, mode.ApplyOnBeat)
+}
+
+func (mode FadeToColorMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.FadeToColor
+}
+
+func (mode FadeToColorMode) Validate() error {
+	return validateFadeDuration(mode.Duration)
+}
+
+func (mode FadeToColorMode) Encode() BoxiBus.MessageBlock {
+	cycles := uint16(FadeDurationToCycles(mode.Duration))
+	return BoxiBus.CreateLightingFadeToColor(mode.Boxi1, mode.Boxi2, cycles, mode.ApplyOnBeat)
+}
+
+func (mode PaletteFadeMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.PaletteFade
+}
+
+func (mode PaletteFadeMode) Validate() error {
+	if err := validatePalette(mode.Palette, mode.Shift); err != nil {
+		return err
+	}
+
+	return validateFadeDuration(mode.Duration)
+}
+
+func (mode PaletteFadeMode) Encode() BoxiBus.MessageBlock {
+	cycles := uint16(FadeDurationToCycles(mode.Duration))
+	return mustEncode(BoxiBus.CreateLightingPaletteFade(mode.Palette, cycles, byte(mode.Shift), mode.ApplyOnBeat))
+}
+
+func (mode PaletteSwitchMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.PaletteSwitch
+}
+
+func (mode PaletteSwitchMode) Validate() error {
+	return validatePalette(mode.Palette, mode.Shift)
+}
+
+func (mode PaletteSwitchMode) Encode() BoxiBus.MessageBlock {
+	return mustEncode(BoxiBus.CreateLightingPaletteSwitch(mode.Palette, byte(mode.Shift), mode.ApplyOnBeat))
+}
+
+func (mode BrightnessFlashMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.PaletteBrightnessFlash
+}
+
+func (mode BrightnessFlashMode) Validate() error {
+	if err := validatePalette(mode.Palette, mode.Shift); err != nil {
+		return err
+	}
+
+	if mode.FadeOutSpeed == 0 {
+		return errors.New("fade out speed must be positive")
+	}
+
+	return validatePercent("target brightness", mode.TargetBrightness)
+}
+
+func (mode BrightnessFlashMode) Encode() BoxiBus.MessageBlock {
+	return mustEncode(BoxiBus.CreateLightingPaletteBrightnessFlash(mode.Palette, mode.FadeOutSpeed, PercentToByte(mode.TargetBrightness),
+		byte(mode.Shift), mode.ApplyOnBeat))
+}
+
+func (mode HueFlashMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.PaletteHueFlash
+}
+
+func (mode HueFlashMode) Validate() error {
+	if err := validatePalette(mode.Palette, mode.Shift); err != nil {
+		return err
+	}
+
+	if mode.FadeOutSpeed == 0 {
+		return errors.New("fade out speed must be positive")
+	}
+
+	return nil
+}
+
+func (mode HueFlashMode) Encode() BoxiBus.MessageBlock {
+	return mustEncode(BoxiBus.CreateLightingPaletteHueFlash(mode.Palette, mode.FadeOutSpeed, byte(mode.Shift), mode.ApplyOnBeat))
+}
+
+func (mode StrobeMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.Strobe
+}
+
+func (mode StrobeMode) Validate() error {
+	speed := StrobeFrequencyToSpeed(mode.Frequency)
+	if speed <= 0 || speed > math.MaxUint16 {
+		return fmt.Errorf("strobe frequency of %.1f Hz is outside of range", mode.Frequency)
+	}
+
+	return nil
+}
+
+func (mode StrobeMode) Encode() BoxiBus.MessageBlock {
+	return BoxiBus.CreateLightingStrobe(mode.Color, uint16(StrobeFrequencyToSpeed(mode.Frequency)), mode.Rolloff, mode.ApplyOnBeat)
+}
+
+func (mode ChaseMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.Chase
+}
+
+func (mode ChaseMode) Validate() error {
+	if err := validatePalette(mode.Palette, 0); err != nil {
+		return err
+	}
+
+	return validatePercent("background brightness", mode.BackgroundBrightness)
+}
+
+func (mode ChaseMode) Encode() BoxiBus.MessageBlock {
+	return mustEncode(BoxiBus.CreateLightingChase(mode.Palette, PercentToByte(mode.BackgroundBrightness), mode.ApplyOnBeat))
+}
+
+func (mode RainbowCycleMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.RainbowCycle
+}
+
+func (mode RainbowCycleMode) Validate() error {
+	if err := validateFadeDuration(mode.Duration); err != nil {
+		return err
+	}
+
+	if mode.HueOffset < 0 || mode.HueOffset >= 360 {
+		return errors.New("hue offset must be between 0 and 359 degrees")
+	}
+
+	return validatePercent("saturation", mode.Saturation)
+}
+
+func (mode RainbowCycleMode) Encode() BoxiBus.MessageBlock {
+	cycles := uint16(FadeDurationToCycles(mode.Duration))
+	hueOffset := byte(math.Round(float64(mode.HueOffset) / 360 * 256))
+	return BoxiBus.CreateLightingRainbowCycle(cycles, hueOffset, PercentToByte(mode.Saturation), mode.ApplyOnBeat)
+}
+
+func (mode BreathingMode) Id() BoxiBus.LightingModeId {
+	return BoxiBus.Breathing
+}
+
+func (mode BreathingMode) Validate() error {
+	if err := validatePalette(mode.Palette, mode.Shift); err != nil {
+		return err
+	}
+
+	if err := validateFadeDuration(mode.Duration); err != nil {
+		return err
+	}
+
+	return validatePercent("minimum brightness", mode.MinBrightness)
+}
+
+func (mode BreathingMode) Encode() BoxiBus.MessageBlock {
+	cycles := uint16(FadeDurationToCycles(mode.Duration))
+	return mustEncode(BoxiBus.CreateLightingBreathing(mode.Palette, cycles, PercentToByte(mode.MinBrightness), byte(mode.Shift),
+		mode.ApplyOnBeat))
+}
+
+// mustEncode returns the block built for a validated mode. The builders only fail for palettes Validate rejects,
+// so an error means Encode was called without validating the mode first.
+func mustEncode(block BoxiBus.MessageBlock, err error) BoxiBus.MessageBlock {
+	if err != nil {
+		panic(fmt.Sprintf("encoding an invalid lighting mode: %s", err))
+	}
+
+	return block
+}
+
+func validatePercent(name string, percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("%s must be between 0 and 100 percent", name)
+	}
+
+	return nil
+}
+
+func validateFadeDuration(duration time.Duration) error {
+	cycles := FadeDurationToCycles(duration)
+	if cycles <= 0 || cycles > math.MaxUint16 {
+		return fmt.Errorf("fade duration of %s is outside of range", duration)
+	}
+
+	return nil
+}
+
+func validatePalette(palette []BoxiBus.Color, shift int) error {
+	if len(palette) == 0 || len(palette) > BoxiBus.MaxPaletteSize {
+		return fmt.Errorf("palette must have between 1 and %d colors", BoxiBus.MaxPaletteSize)
+	}
+
+	//The Arduino wraps the shift around the palette
+	if shift < 0 || shift >= BoxiBus.MaxPaletteSize {
+		return fmt.Errorf("palette shift must be between 0 and %d", BoxiBus.MaxPaletteSize-1)
+	}
+
+	return nil
+}
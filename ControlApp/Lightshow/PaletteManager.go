@@ -3,6 +3,7 @@ package Lightshow
 import (
 	"ControlApp/BoxiBus"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"sort"
@@ -19,22 +20,40 @@ type Palette struct {
 	Name   string
 	Colors []BoxiBus.Color
 	Moods  []LightingMood
+	Weight float64 //Relative chance of being picked by auto mode, 0 for the default weight
+}
+
+func (palette Palette) selectionId() uint32 {
+	return palette.Id
+}
+
+// GetWeight returns the relative chance of the palette being picked by auto mode.
+func (palette Palette) GetWeight() float64 {
+	return getWeight(palette.Weight)
 }
 
 const palettesConfigPath = "Configuration/palettes.json"
 const palettesConfigBackupPath = "Configuration/palettes_backup.json"
 
 func LoadPalettes() *PaletteManager {
-	config, err := loadConfiguration[map[uint32]Palette](palettesConfigPath)
+	config, err := LoadConfiguration[map[uint32]Palette](palettesConfigPath)
 
 	if err != nil {
-		config, err = loadConfiguration[map[uint32]Palette](palettesConfigBackupPath)
+		config, err = LoadConfiguration[map[uint32]Palette](palettesConfigBackupPath)
 	}
 
 	if err != nil {
 		log.Fatalf("Config file for palettes could not be accessed! %s", err)
 	}
 
+	for id, palette := range config {
+		if len(palette.Colors) > BoxiBus.MaxPaletteSize {
+			log.Printf("Palette '%s' has more than %d colors, the rest is dropped.", palette.Name, BoxiBus.MaxPaletteSize)
+			palette.Colors = palette.Colors[:BoxiBus.MaxPaletteSize]
+			config[id] = palette
+		}
+	}
+
 	return &PaletteManager{
 		palettes:   config,
 		accessLock: &sync.Mutex{},
@@ -109,12 +128,21 @@ func (manager *PaletteManager) GetAll() []Palette {
 	return palettes
 }
 
-func (manager *PaletteManager) SetPalette(palette Palette) {
+func (manager *PaletteManager) SetPalette(palette Palette) error {
+	if len(palette.Colors) == 0 || len(palette.Colors) > BoxiBus.MaxPaletteSize {
+		return fmt.Errorf("a palette must have between 1 and %d colors", BoxiBus.MaxPaletteSize)
+	}
+
+	if !ValidateWeight(palette.Weight) {
+		return fmt.Errorf("the weight of a palette must be between 0 and %g", MaxWeight)
+	}
+
 	manager.accessLock.Lock()
 	defer manager.accessLock.Unlock()
 
 	manager.palettes[palette.Id] = palette
 	manager.storeConfiguration()
+	return nil
 }
 
 func (manager *PaletteManager) RemovePalette(paletteId uint32) {
@@ -135,6 +163,6 @@ func getDefaultPalettes() []Palette {
 				{0, 255, 255, 0, 0, 0},
 				{0, 0, 255, 0, 0, 0},
 				{255, 0, 255, 0, 0, 0},
-			}, []LightingMood{Moody, Happy, Regular, Party}},
+			}, []LightingMood{Moody, Happy, Regular, Party}, DefaultWeight},
 	}
 }
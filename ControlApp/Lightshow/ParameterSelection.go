@@ -3,7 +3,9 @@ package Lightshow
 import (
 	"ControlApp/BoxiBus"
 	"ControlApp/Display"
+	"log"
 	"math/rand"
+	"slices"
 )
 
 type switchType uint8
@@ -16,7 +18,9 @@ const (
 )
 
 const (
-	defaultBlinkSpeed = 600
+	defaultBlinkSpeed     = 600
+	paletteRepeatWindow   = 1 //Other palettes picked before a palette is picked again
+	animationRepeatWindow = 4 //Other animations picked before an animation is played again, as many as a switch has displays
 )
 
 func (context *AutoModeContext) getNextAnimation(switchType switchType) AnimationsInstruction {
@@ -24,7 +28,7 @@ func (context *AutoModeContext) getNextAnimation(switchType switchType) Animatio
 
 	//When in a calmer section of a beat mode, randomly pick between moody and happy
 	if (baseMood == Regular || baseMood == Party) && (switchType == InDeadTime || switchType == InCalmMode) {
-		randNbr := rand.Intn(2)
+		randNbr := context.random.Intn(2)
 		if randNbr == 0 {
 			baseMood = Moody
 		} else {
@@ -37,27 +41,26 @@ func (context *AutoModeContext) getNextAnimation(switchType switchType) Animatio
 	defer animationManager.accessLock.Unlock()
 
 	//Find valid animations to switch to
-	validIds := make([]Display.AnimationId, 0)
-	for id, animation := range animationManager.animations {
+	validAnimations := make([]Animation, 0)
+	for _, animation := range animationManager.animations {
 		if animation.IsNsfw && !context.Configuration.AllowNsfw {
 			continue
 		}
 
 		if animation.Mood == baseMood || animation.Mood == Regular && baseMood == Party {
-			validIds = append(validIds, id)
+			validAnimations = append(validAnimations, animation)
 		}
 	}
 
-	if len(validIds) < 1 {
+	if len(validAnimations) < 1 {
 		return AnimationsInstruction{Character: Unknown}
 	}
 
 	var dsp1A, dsp1B, dsp2A, dsp2B Display.AnimationId
 
-	mirrorAcrossScreens := rand.Intn(3)
+	mirrorAcrossScreens := context.random.Intn(3)
 	generateBoxiScreens := func() (Display.AnimationId, Display.AnimationId) {
-		randomIndex := rand.Intn(len(validIds))
-		firstAnimation := animationManager.animations[validIds[randomIndex]]
+		firstAnimation := pickWeighted(context.animationHistory, validAnimations, context.random)
 
 		//If picked animation is played across two screens, do that
 		if firstAnimation.SecondaryAnimation != Display.None {
@@ -68,14 +71,13 @@ func (context *AutoModeContext) getNextAnimation(switchType switchType) Animatio
 			return firstAnimation.Id, firstAnimation.Id
 		}
 
-		randomIndex = rand.Intn(len(validIds))
-		secondAnimation := animationManager.animations[validIds[randomIndex]]
+		secondAnimation := pickWeighted(context.animationHistory, validAnimations, context.random)
 		return firstAnimation.Id, secondAnimation.Id
 	}
 
 	dsp1A, dsp1B = generateBoxiScreens()
 
-	mirrorAcrossBoxis := rand.Intn(2)
+	mirrorAcrossBoxis := context.random.Intn(2)
 	if mirrorAcrossBoxis == 0 {
 		dsp2A = dsp1A
 		dsp2B = dsp1B
@@ -83,7 +85,7 @@ func (context *AutoModeContext) getNextAnimation(switchType switchType) Animatio
 		dsp2A, dsp2B = generateBoxiScreens()
 	}
 
-	doDaBounce := rand.Intn(10)
+	doDaBounce := context.random.Intn(10)
 	var blinkSpeed uint16
 	if doDaBounce == 6 && baseMood == Party {
 		blinkSpeed = defaultBlinkSpeed
@@ -107,18 +109,24 @@ func (context *AutoModeContext) getNextAnimation(switchType switchType) Animatio
 	for animationId, display := range screensPerAnimation {
 		instructions = append(instructions, AnimationInstruction{animationId, []Display.ServerDisplay{display}})
 	}
+	slices.SortFunc(instructions, func(a, b AnimationInstruction) int {
+		return int(a.Displays[0]) - int(b.Displays[0])
+	})
 
-	return AnimationsInstruction{instructions, character, blinkSpeed}
+	instruction := AnimationsInstruction{instructions, character, blinkSpeed}
+	context.animations = &instruction
+	return instruction
 }
 
 func (context *AutoModeContext) getNextLighting(switchType switchType) LightingInstruction {
 	baseMood := context.Configuration.Mood
 	var possibleModes []BoxiBus.LightingModeId
 	var possiblePalettes []Palette
+	history := context.paletteHistory
 
 	if (baseMood == Regular || baseMood == Party) && (switchType == InDeadTime || switchType == InCalmMode) {
 		//When in a calmer section of a beat mode, randomly pick between moody and happy
-		randNbr := rand.Intn(2)
+		randNbr := context.random.Intn(2)
 		if randNbr == 0 {
 			baseMood = Moody
 		} else {
@@ -129,10 +137,13 @@ func (context *AutoModeContext) getNextLighting(switchType switchType) LightingI
 		if switchType == InCalmMode {
 			possibleModes = []BoxiBus.LightingModeId{BoxiBus.FadeToColor}
 			possiblePalettes = []Palette{
-				{0, "UV", []BoxiBus.Color{{0, 0, 10, 0, 0, 255}}, nil},
-				{1, "Blue", []BoxiBus.Color{{0, 0, 255, 0, 0, 0}}, nil},
-				{2, "Amber", []BoxiBus.Color{{0, 0, 0, 0, 255, 0}}, nil},
+				{0, "UV", []BoxiBus.Color{{0, 0, 10, 0, 0, 255}}, nil, DefaultWeight},
+				{1, "Blue", []BoxiBus.Color{{0, 0, 255, 0, 0, 0}}, nil, DefaultWeight},
+				{2, "Amber", []BoxiBus.Color{{0, 0, 0, 0, 255, 0}}, nil, DefaultWeight},
 			}
+
+			//These aren't configured palettes and their IDs could be mistaken for them
+			history = createSelectionHistory(0)
 		} else {
 			possibleModes = []BoxiBus.LightingModeId{BoxiBus.PaletteFade}
 		}
@@ -141,7 +152,10 @@ func (context *AutoModeContext) getNextLighting(switchType switchType) LightingI
 	if possibleModes == nil {
 		possibleModes = getLightingModesByMood(baseMood)
 	}
-	randNbr := rand.Intn(len(possibleModes))
+	possibleModes = slices.DeleteFunc(slices.Clone(possibleModes), func(mode BoxiBus.LightingModeId) bool {
+		return !context.manager.SupportsLightingMode(mode)
+	})
+	randNbr := context.random.Intn(len(possibleModes))
 	mode := possibleModes[randNbr]
 
 	if possiblePalettes == nil {
@@ -150,14 +164,15 @@ func (context *AutoModeContext) getNextLighting(switchType switchType) LightingI
 	if possiblePalettes == nil || len(possiblePalettes) == 0 {
 		possiblePalettes = getDefaultPalettes()
 	}
-	randNbr = rand.Intn(len(possiblePalettes))
-	palette := possiblePalettes[randNbr].Colors
+	pickedPalette := pickWeighted(history, possiblePalettes, context.random)
+	palette := pickedPalette.Colors
+	paletteName := pickedPalette.Name
 
-	randNbr = rand.Intn(context.Configuration.HueShiftChance)
+	randNbr = context.random.Intn(context.Configuration.HueShiftChance)
 	hueShift := 0
 
 	if randNbr == 0 {
-		hueShift = rand.Intn(len(palette))
+		hueShift = context.random.Intn(len(palette))
 	}
 
 	// Figure out whether the mode should be applied on the next beat
@@ -172,59 +187,99 @@ func (context *AutoModeContext) getNextLighting(switchType switchType) LightingI
 		if switchType == OnBeat {
 			chance *= 2
 		}
-		randNbr = rand.Intn(chance)
+		randNbr = context.random.Intn(chance)
 		if randNbr == 0 {
 			mode = BoxiBus.Strobe
 			palette = []BoxiBus.Color{{0, 0, 0, 255, 0, 0}}
+			paletteName = "White"
 			applyOnNextBeat = false
 		}
 	}
 
-	messages := getLightingMessages(context.Configuration, mode, palette, byte(hueShift), applyOnNextBeat)
-	character := getLightingModeCharacter(mode)
-	return LightingInstruction{messages, character}
+	lightingMode := getLightingMode(context.Configuration, mode, palette, byte(hueShift), applyOnNextBeat)
+	if err := lightingMode.Validate(); err != nil {
+		//Keep the current lighting
+		log.Printf("Auto mode picked invalid %s mode: %s", mode, err)
+		return LightingInstruction{nil, getLightingModeCharacter(mode)}
+	}
+
+	instruction := CreateLightingInstruction(lightingMode)
+	modes := [2]BoxiBus.LightingModeId{mode, mode}
+
+	//Let Boxi 2 run a different mode of the same selection on the opposite side of the palette
+	independentBoxis := context.Configuration.IndependentBoxis && context.manager.SupportsPerBoxiLighting()
+	if independentBoxis && mode != BoxiBus.Strobe && len(possibleModes) > 1 {
+		complementaryMode := getComplementaryMode(mode, possibleModes, context.random)
+		complementaryShift := (hueShift + len(palette)/2) % len(palette)
+		boxi2Mode := getLightingMode(context.Configuration, complementaryMode, palette, byte(complementaryShift), applyOnNextBeat)
+		if boxi2Mode.Validate() == nil {
+			instruction = CreateLightingInstructionPerBoxi(lightingMode, boxi2Mode)
+			modes[1] = complementaryMode
+		}
+	}
+
+	context.lighting = &AutoModeLighting{paletteName, modes, instruction.character}
+	return instruction
+}
+
+// getComplementaryMode randomly picks another mode than the given one out of the possible modes.
+func getComplementaryMode(mode BoxiBus.LightingModeId, possibleModes []BoxiBus.LightingModeId, random *rand.Rand) BoxiBus.LightingModeId {
+	var candidates []BoxiBus.LightingModeId
+	for _, candidate := range possibleModes {
+		if candidate != mode {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return mode
+	}
+
+	return candidates[random.Intn(len(candidates))]
 }
 
 func getLightingModesByMood(mood LightingMood) []BoxiBus.LightingModeId {
 	switch mood {
-	case Happy, Moody:
-		return []BoxiBus.LightingModeId{BoxiBus.FadeToColor, BoxiBus.PaletteFade}
+	case Happy:
+		return []BoxiBus.LightingModeId{BoxiBus.FadeToColor, BoxiBus.PaletteFade, BoxiBus.RainbowCycle}
+	case Moody:
+		return []BoxiBus.LightingModeId{BoxiBus.FadeToColor, BoxiBus.PaletteFade, BoxiBus.Breathing}
 	default:
-		return []BoxiBus.LightingModeId{BoxiBus.PaletteSwitch, BoxiBus.PaletteBrightnessFlash, BoxiBus.PaletteHueFlash}
+		return []BoxiBus.LightingModeId{BoxiBus.PaletteSwitch, BoxiBus.PaletteBrightnessFlash, BoxiBus.PaletteHueFlash, BoxiBus.Chase}
 	}
 }
 
-func getLightingMessages(config AutoModeConfiguration, mode BoxiBus.LightingModeId, palette []BoxiBus.Color, hueShift byte, applyOnBeat bool) BoxiBus.MessageBlock {
+func getLightingMode(config AutoModeConfiguration, mode BoxiBus.LightingModeId, palette []BoxiBus.Color, hueShift byte, applyOnBeat bool) LightingMode {
 	switch mode {
-	case BoxiBus.Off:
-		return BoxiBus.CreateLightingOff(applyOnBeat)
 	case BoxiBus.SetColor:
-		return BoxiBus.CreateLightingSetColor(palette[0], palette[int(hueShift)%len(palette)], applyOnBeat)
+		return SetColorMode{palette[0], palette[int(hueShift)%len(palette)], applyOnBeat}
 	case BoxiBus.FadeToColor:
-		return BoxiBus.CreateLightingFadeToColor(palette[0], palette[int(hueShift)%len(palette)], config.FadeToColorCycles, applyOnBeat)
+		duration := CyclesToFadeDuration(config.FadeToColorCycles)
+		return FadeToColorMode{palette[0], palette[int(hueShift)%len(palette)], duration, applyOnBeat}
 	case BoxiBus.PaletteFade:
-		result, err := BoxiBus.CreateLightingPaletteFade(palette, config.PaletteFadeCycles, hueShift, applyOnBeat)
-		if err == nil {
-			return result
-		}
+		return PaletteFadeMode{palette, CyclesToFadeDuration(config.PaletteFadeCycles), int(hueShift), applyOnBeat}
 	case BoxiBus.PaletteSwitch:
-		result, err := BoxiBus.CreateLightingPaletteSwitch(palette, hueShift, applyOnBeat)
-		if err == nil {
-			return result
-		}
+		return PaletteSwitchMode{palette, int(hueShift), applyOnBeat}
 	case BoxiBus.PaletteBrightnessFlash:
-		result, err := BoxiBus.CreateLightingPaletteBrightnessFlash(palette, config.FlashFadeoutSpeed, config.FlashTargetBrightness, hueShift, applyOnBeat)
-		if err == nil {
-			return result
-		}
+		brightness := ByteToPercent(config.FlashTargetBrightness)
+		return BrightnessFlashMode{palette, config.FlashFadeoutSpeed, brightness, int(hueShift), applyOnBeat}
 	case BoxiBus.PaletteHueFlash:
-		result, err := BoxiBus.CreateLightingPaletteHueFlash(palette, config.HueFlashFadeoutSpeed, config.FlashHueShift, applyOnBeat)
-		if err == nil {
-			return result
-		}
+		return HueFlashMode{palette, config.HueFlashFadeoutSpeed, int(config.FlashHueShift), applyOnBeat}
 	case BoxiBus.Strobe:
-		return BoxiBus.CreateLightingStrobe(palette[0], config.StrobeFrequency, config.StrobeRolloff, applyOnBeat)
+		return StrobeMode{palette[0], StrobeSpeedToFrequency(config.StrobeFrequency), config.StrobeRolloff, applyOnBeat}
+	case BoxiBus.Chase:
+		return ChaseMode{palette, ByteToPercent(config.ChaseBackgroundBrightness), applyOnBeat}
+	case BoxiBus.RainbowCycle:
+		//Put the Boxis on opposite hues when the palette would have been shifted
+		hueOffset := 0
+		if hueShift != 0 {
+			hueOffset = 180
+		}
+		return RainbowCycleMode{CyclesToFadeDuration(config.RainbowCycleCycles), hueOffset, 100, applyOnBeat}
+	case BoxiBus.Breathing:
+		duration := CyclesToFadeDuration(config.BreathingCycles)
+		return BreathingMode{palette, duration, ByteToPercent(config.BreathingMinBrightness), int(hueShift), applyOnBeat}
 	}
 
-	return nil
+	return OffMode{applyOnBeat}
 }
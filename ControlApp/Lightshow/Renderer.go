@@ -0,0 +1,312 @@
+package Lightshow
+
+import (
+	"ControlApp/BoxiBus"
+	"ControlApp/Infrastructure"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	renderCyclesPerSecond = Infrastructure.FadeDurationMsToCycles * 1000 //The rate of the Arduino's render loop
+	calmColorBrightness   = 0.7                                          //Non-pulsed modes are dimmed to keep the LEDs from overheating
+	strobePhases          = 6                                            //The strobe is lit for one of six phases of speed cycles
+	flashDecay            = -0.01                                        //Exponent of the flash fade out per cycle and speed unit
+)
+
+// floatColor holds the RGBWAUV channels from 0 to 1, like the firmware calculates them.
+type floatColor [6]float64
+
+// Renderer simulates the render loop of the Arduino firmware frame by frame, so the colors the Boxis
+// show are known at any moment. It mirrors the Arduino memory and gets the same lighting instructions
+// and beats as the Arduino.
+type Renderer struct {
+	lock        *sync.Mutex
+	memory      *BoxiBus.ArduinoMemory
+	boxis       [2]boxiRenderState
+	pendingBeat bool
+	brightness  float64 //The master brightness set at the Boxis
+	cycles      float64 //Cycles that are due but not rendered yet
+	colors      [2]BoxiBus.Color
+}
+
+// boxiRenderState is the state of the render loop for a single Boxi.
+type boxiRenderState struct {
+	reference floatColor //The color fades start from
+	last      floatColor //The color of the last cycle, before dimming
+	counter   int
+	index     int
+	applied   bool //The lighting was applied since the last cycle
+}
+
+// CreateRenderer returns a renderer in the state the firmware boots into.
+func CreateRenderer() *Renderer {
+	renderer := &Renderer{lock: &sync.Mutex{}, memory: BoxiBus.CreateArduinoMemory(), brightness: 1}
+	for i := range renderer.boxis {
+		renderer.boxis[i].reference = floatColor{1}
+		renderer.boxis[i].last = floatColor{1}
+	}
+
+	return renderer
+}
+
+// Write passes a lighting instruction to the renderer. Messages the Arduino would reject are skipped.
+func (renderer *Renderer) Write(block BoxiBus.MessageBlock) {
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+
+	for _, message := range block {
+		applied, err := renderer.memory.Write(message)
+		if err == nil {
+			renderer.apply(applied)
+		}
+	}
+}
+
+// Beat signals a beat, which takes effect in the next cycle.
+func (renderer *Renderer) Beat() {
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+
+	renderer.pendingBeat = true
+}
+
+// SetMasterBrightness sets the brightness all colors are dimmed by, from 0 to 1.
+func (renderer *Renderer) SetMasterBrightness(brightness float64) {
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+
+	renderer.brightness = min(max(brightness, 0), 1)
+}
+
+// GetColors returns the colors of Boxi 1 and Boxi 2 in the last rendered cycle.
+func (renderer *Renderer) GetColors() [2]BoxiBus.Color {
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+
+	return renderer.colors
+}
+
+// GetModes returns the lighting modes Boxi 1 and Boxi 2 are running.
+func (renderer *Renderer) GetModes() [2]BoxiBus.LightingModeId {
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+
+	return [2]BoxiBus.LightingModeId{
+		renderer.memory.Active(BoxiBus.TargetBoxi1).Mode,
+		renderer.memory.Active(BoxiBus.TargetBoxi2).Mode,
+	}
+}
+
+// Advance renders all cycles that are due after the given time passed and returns the resulting colors.
+func (renderer *Renderer) Advance(elapsed time.Duration) [2]BoxiBus.Color {
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+
+	renderer.cycles += elapsed.Seconds() * renderCyclesPerSecond
+	for ; renderer.cycles >= 1; renderer.cycles-- {
+		renderer.step()
+	}
+
+	return renderer.colors
+}
+
+// Step renders a single cycle and returns the resulting colors.
+func (renderer *Renderer) Step() [2]BoxiBus.Color {
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+
+	renderer.step()
+	return renderer.colors
+}
+
+// step renders a single cycle. Requires the lock.
+func (renderer *Renderer) step() {
+	isBeat := renderer.pendingBeat
+	renderer.pendingBeat = false
+	if isBeat {
+		renderer.apply(renderer.memory.Beat())
+	}
+
+	for i, target := range []BoxiBus.LightingTarget{BoxiBus.TargetBoxi1, BoxiBus.TargetBoxi2} {
+		state := &renderer.boxis[i]
+
+		//Applying lighting counts as a beat, so beat based modes start right away
+		color, limit := renderBoxi(renderer.memory.Active(target), i, state, isBeat || state.applied)
+		state.last = color
+		state.applied = false
+
+		renderer.colors[i] = color.toBusColor(limit * renderer.brightness)
+	}
+}
+
+// apply resets the render state of the Boxis whose lighting was applied. Requires the lock.
+func (renderer *Renderer) apply(applied BoxiBus.LightingTarget) {
+	for i, target := range []BoxiBus.LightingTarget{BoxiBus.TargetBoxi1, BoxiBus.TargetBoxi2} {
+		if applied&target == 0 {
+			continue
+		}
+
+		state := &renderer.boxis[i]
+		state.reference = state.last
+		state.counter = 0
+		state.index = 0
+		state.applied = true
+	}
+}
+
+// renderBoxi calculates the color of a Boxi for one cycle, along with the brightness limit of the mode.
+// Boxi 1 has side 0 and Boxi 2 has side 1.
+func renderBoxi(fieldSet BoxiBus.LightingFieldSet, side int, state *boxiRenderState, isBeat bool) (floatColor, float64) {
+	palette := fieldSet.Palette[:min(fieldSet.PaletteSize, BoxiBus.MaxPaletteSize)]
+	speed := max(int(fieldSet.Speed), 1)
+	shift := side * int(fieldSet.ColorShift)
+
+	//The color at the palette index, shifted for Boxi 2
+	paletteColor := func(index int) floatColor {
+		if len(palette) == 0 {
+			return floatColor{}
+		}
+		return convertFloatColor(palette[(index+shift)%len(palette)])
+	}
+
+	switch fieldSet.Mode {
+	case BoxiBus.SetColor:
+		return convertFloatColor(fieldSet.Palette[side]), calmColorBrightness
+	case BoxiBus.FadeToColor:
+		progress := min(float64(state.counter)/float64(speed), 1)
+		state.counter = min(state.counter+1, math.MaxUint16)
+		return state.reference.lerp(convertFloatColor(fieldSet.Palette[side]), progress), calmColorBrightness
+	case BoxiBus.PaletteFade:
+		color := state.reference.lerp(paletteColor(state.index), float64(state.counter)/float64(speed))
+		if state.counter++; state.counter > speed {
+			state.counter = 0
+			state.index = (state.index + 1) % max(len(palette), 1)
+			state.reference = color
+		}
+		return color, calmColorBrightness
+	case BoxiBus.PaletteSwitch:
+		if isBeat {
+			state.index = (state.index + 1) % max(len(palette), 1)
+		}
+		return paletteColor(state.index), calmColorBrightness
+	case BoxiBus.PaletteBrightnessFlash:
+		if isBeat {
+			state.index = (state.index + 1) % max(len(palette), 1)
+			state.counter = 0
+		}
+		flash := math.Exp(float64(state.counter)*flashDecay*float64(fieldSet.Speed))*5 + float64(fieldSet.GeneralPurpose)/255
+		state.counter = min(state.counter+1, math.MaxUint16)
+		return paletteColor(state.index).multiply(min(flash, 1)), 1
+	case BoxiBus.PaletteHueFlash:
+		if isBeat {
+			state.index = (state.index + 2) % max(len(palette), 1)
+			state.counter = 0
+		}
+		if len(palette) == 0 {
+			return floatColor{}, calmColorBrightness
+		}
+		startColor := convertFloatColor(palette[state.index%len(palette)])
+		endColor := convertFloatColor(palette[(state.index+int(fieldSet.ColorShift))%len(palette)])
+		flash := math.Exp(float64(state.counter)*flashDecay*float64(fieldSet.Speed)) * 5
+		state.counter = min(state.counter+1, math.MaxUint16)
+		return startColor.lerp(endColor, min(flash, 1)), calmColorBrightness
+	case BoxiBus.Strobe:
+		//The firmware ignores the rolloff and wraps its 16 bit counter around, the flash is either on or off
+		brightness := 0.0
+		if (state.counter/speed)%strobePhases == 0 {
+			brightness = 1
+		}
+		state.counter = (state.counter + 1) % (math.MaxUint16 + 1)
+		return convertFloatColor(fieldSet.Palette[0]).multiply(brightness), 1
+	case BoxiBus.Chase:
+		if isBeat {
+			state.index = (state.index + 1) % max(len(palette), 1)
+		}
+		if len(palette) == 0 {
+			return floatColor{}, calmColorBrightness
+		}
+		//Every beat, the other Boxi lights up, starting with Boxi 1. Both show the same color.
+		color := convertFloatColor(palette[state.index%len(palette)])
+		if (state.index+1)%2 != side {
+			color = color.multiply(float64(fieldSet.GeneralPurpose) / 255)
+		}
+		return color, calmColorBrightness
+	case BoxiBus.RainbowCycle:
+		hue := float64(state.counter)/float64(speed) + float64(side)*float64(fieldSet.ColorShift)/256
+		state.counter = (state.counter + 1) % speed
+		return getHueColor(hue, float64(fieldSet.GeneralPurpose)/255), calmColorBrightness
+	case BoxiBus.Breathing:
+		//Starts at full brightness, the next color is picked at the darkest point
+		minBrightness := float64(fieldSet.GeneralPurpose) / 255
+		phase := float64(state.counter) / float64(speed)
+		brightness := minBrightness + (1-minBrightness)*(0.5+0.5*math.Cos(2*math.Pi*phase))
+		color := paletteColor(state.index).multiply(brightness)
+		if state.counter++; state.counter == speed/2 {
+			state.index = (state.index + 1) % max(len(palette), 1)
+		}
+		state.counter %= speed
+		return color, calmColorBrightness
+	}
+
+	return floatColor{}, calmColorBrightness
+}
+
+func convertFloatColor(color BoxiBus.Color) floatColor {
+	return floatColor{
+		float64(color.Red) / 255,
+		float64(color.Green) / 255,
+		float64(color.Blue) / 255,
+		float64(color.White) / 255,
+		float64(color.Amber) / 255,
+		float64(color.UltraViolet) / 255,
+	}
+}
+
+// toBusColor dims the color by the factor and truncates it to bytes, just like the firmware.
+func (color floatColor) toBusColor(factor float64) BoxiBus.Color {
+	var channels [6]byte
+	for i, value := range color {
+		channels[i] = byte(min(max(value*factor, 0), 1) * 255)
+	}
+
+	return BoxiBus.Color{
+		Red:         channels[0],
+		Green:       channels[1],
+		Blue:        channels[2],
+		White:       channels[3],
+		Amber:       channels[4],
+		UltraViolet: channels[5],
+	}
+}
+
+func (color floatColor) lerp(target floatColor, progress float64) floatColor {
+	var result floatColor
+	for i := range color {
+		result[i] = color[i]*(1-progress) + target[i]*progress
+	}
+
+	return result
+}
+
+func (color floatColor) multiply(factor float64) floatColor {
+	var result floatColor
+	for i := range color {
+		result[i] = color[i] * factor
+	}
+
+	return result
+}
+
+// getHueColor converts a hue given in rotations and a saturation to a color at full brightness.
+func getHueColor(hue float64, saturation float64) floatColor {
+	channel := func(offset float64) float64 {
+		//Distance of the hue to the primary color, in sixths of a rotation
+		distance := math.Abs(math.Mod(math.Mod(hue*6+offset, 6)+6, 6) - 3)
+		value := min(max(distance-1, 0), 1)
+		return 1 - saturation*(1-value)
+	}
+
+	return floatColor{channel(0), channel(4), channel(2)}
+}
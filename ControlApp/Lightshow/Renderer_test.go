@@ -0,0 +1,45 @@
+package Lightshow
+
+import (
+	"ControlApp/BoxiBus"
+	"testing"
+)
+
+// TestRenderStrobe checks the strobe against the firmware, which lights the color while
+// (counter / speed) % 6 == 0 and ignores the rolloff.
+func TestRenderStrobe(t *testing.T) {
+	white := BoxiBus.Color{White: 255}
+
+	for _, rolloff := range []byte{0, 3} {
+		renderer := CreateRenderer()
+		renderer.Write(BoxiBus.CreateLightingStrobe(white, 2, rolloff, false))
+
+		for cycle := 0; cycle < 30; cycle++ {
+			colors := renderer.Step()
+
+			expected := BoxiBus.Color{}
+			if (cycle/2)%6 == 0 {
+				expected = white
+			}
+
+			if colors[0] != expected || colors[1] != expected {
+				t.Fatalf("rolloff %d, cycle %d: rendered %s and %s, expected %s", rolloff, cycle, colors[0], colors[1], expected)
+			}
+		}
+	}
+}
+
+// TestRenderStrobeCounterWrapsAround checks that the phase restarts when the 16 bit counter of the firmware overflows.
+func TestRenderStrobeCounterWrapsAround(t *testing.T) {
+	renderer := CreateRenderer()
+	renderer.Write(BoxiBus.CreateLightingStrobe(BoxiBus.Color{Red: 255}, 7, 0, false))
+
+	//65535 / 7 = 9362 rem 1, so the last cycles before the overflow are in phase 9362 % 6 = 2 and dark
+	for cycle := 0; cycle < 65536; cycle++ {
+		renderer.Step()
+	}
+
+	if colors := renderer.Step(); colors[0].Red != 255 {
+		t.Errorf("rendered %s after the counter overflowed, expected the strobe to be lit", colors[0])
+	}
+}
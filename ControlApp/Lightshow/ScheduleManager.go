@@ -0,0 +1,217 @@
+package Lightshow
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const scheduleConfigPath = "Configuration/schedule.json"
+const scheduleConfigBackupPath = "Configuration/schedule_backup.json"
+
+// ShowProgram sets the mood of the show from a time of the night on, until the next program starts.
+type ShowProgram struct {
+	Start      string //Time of day the program starts at, as HH:MM
+	Mood       LightingMood
+	AllowNsfw  bool
+	Brightness int //Display brightness in percent
+}
+
+// Schedule is the timeline of show programs, it repeats every day.
+type Schedule struct {
+	Enabled  bool
+	Programs []ShowProgram
+}
+
+// ScheduleManager switches to the show program of the current time of day. Changes by hand last until
+// the next program starts.
+type ScheduleManager struct {
+	schedule Schedule
+	apply    func(program ShowProgram)
+	clock    Clock
+	changed  chan struct{} //Wakes up the scheduler when the schedule was changed
+	lock     *sync.Mutex
+
+	nextSwitch time.Time //When the applied program ends, zero if none is applied. Only used by the scheduler
+}
+
+// LoadSchedule reads the schedule, falling back to the backup. The programs are passed to the given function once they start.
+func LoadSchedule(apply func(program ShowProgram)) *ScheduleManager {
+	config, err := LoadConfiguration[Schedule](scheduleConfigPath)
+	if err != nil {
+		config, err = LoadConfiguration[Schedule](scheduleConfigBackupPath)
+	}
+
+	if err != nil {
+		log.Fatalf("Config file for the schedule could not be accessed! %s", err)
+	}
+
+	if err := config.validate(); err != nil {
+		log.Fatalf("Config file for the schedule is invalid! %s", err)
+	}
+
+	return &ScheduleManager{
+		schedule: config,
+		apply:    apply,
+		clock:    systemClock{},
+		changed:  make(chan struct{}, 1),
+		lock:     &sync.Mutex{},
+	}
+}
+
+func (manager *ScheduleManager) storeConfiguration() {
+	StoreConfiguration(&manager.schedule, scheduleConfigPath, scheduleConfigBackupPath)
+}
+
+// GetSchedule returns a copy of the schedule.
+func (manager *ScheduleManager) GetSchedule() Schedule {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	schedule := manager.schedule
+	schedule.Programs = slices.Clone(manager.schedule.Programs)
+	return schedule
+}
+
+// SetSchedule replaces and stores the schedule. If it is enabled, the current program is applied right away.
+func (manager *ScheduleManager) SetSchedule(schedule Schedule) error {
+	if err := schedule.validate(); err != nil {
+		return err
+	}
+
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	manager.schedule = schedule
+	manager.storeConfiguration()
+
+	select {
+	case manager.changed <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// run applies each program when it starts, until the application stops.
+func (manager *ScheduleManager) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		if wait, ok := manager.applyDueProgram(); ok {
+			timer.Reset(wait)
+		} else {
+			timer.Stop()
+		}
+
+		select {
+		case <-timer.C:
+		case <-manager.changed:
+			manager.nextSwitch = time.Time{}
+		}
+	}
+}
+
+// applyDueProgram applies the current program if it wasn't applied since it started, so a single program is
+// applied again every day. Returns how long until the next program starts, false if the schedule is disabled or empty.
+func (manager *ScheduleManager) applyDueProgram() (time.Duration, bool) {
+	now := manager.clock.Now()
+	program, nextStart, ok := manager.getCurrentProgram(now)
+	if !ok {
+		manager.nextSwitch = time.Time{}
+		return 0, false
+	}
+
+	if !now.Before(manager.nextSwitch) {
+		log.Printf("Switching to the show program starting at %s", program.Start)
+		manager.apply(program)
+	}
+
+	manager.nextSwitch = nextStart
+	return nextStart.Sub(now), true
+}
+
+// getCurrentProgram returns the program running at the given time and when the next one starts. The second
+// value is false if the schedule is disabled or empty.
+func (manager *ScheduleManager) getCurrentProgram(now time.Time) (ShowProgram, time.Time, bool) {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	if !manager.schedule.Enabled || len(manager.schedule.Programs) == 0 {
+		return ShowProgram{}, time.Time{}, false
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	sinceMidnight := now.Sub(midnight)
+
+	//Validated programs are sorted by their start, the last one keeps running past midnight until the first one starts
+	programs := manager.schedule.Programs
+	current := programs[len(programs)-1]
+	nextStart := midnight.AddDate(0, 0, 1).Add(mustParseTimeOfDay(programs[0].Start))
+	for _, program := range programs {
+		start := mustParseTimeOfDay(program.Start)
+		if start > sinceMidnight {
+			nextStart = midnight.Add(start)
+			break
+		}
+
+		current = program
+	}
+
+	return current, nextStart, true
+}
+
+// validate checks the programs, then formats their start as HH:MM and sorts them by it.
+func (schedule *Schedule) validate() error {
+	for i, program := range schedule.Programs {
+		start, err := ParseTimeOfDay(program.Start)
+		if err != nil {
+			return err
+		}
+		schedule.Programs[i].Start = fmt.Sprintf("%02d:%02d", int(start.Hours()), int(start.Minutes())%60)
+
+		if program.Mood > Party {
+			return fmt.Errorf("illegal mood value '%d' at %s", program.Mood, program.Start)
+		}
+
+		if program.Brightness < 0 || program.Brightness > 100 {
+			return fmt.Errorf("the brightness at %s must be between 0 and 100 percent", program.Start)
+		}
+	}
+
+	slices.SortFunc(schedule.Programs, func(a, b ShowProgram) int {
+		return int(mustParseTimeOfDay(a.Start) - mustParseTimeOfDay(b.Start))
+	})
+
+	for i := 1; i < len(schedule.Programs); i++ {
+		if mustParseTimeOfDay(schedule.Programs[i].Start) == mustParseTimeOfDay(schedule.Programs[i-1].Start) {
+			return fmt.Errorf("two programs start at %s", schedule.Programs[i].Start)
+		}
+	}
+
+	return nil
+}
+
+// ParseTimeOfDay returns the time since midnight of a time formatted as HH:MM.
+func ParseTimeOfDay(value string) (time.Duration, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(strings.TrimSpace(value), "%d:%d", &hours, &minutes); err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s', expected HH:MM", value)
+	}
+
+	if hours < 0 || hours > 23 || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid time of day '%s', expected HH:MM", value)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// mustParseTimeOfDay parses a time of day that was already validated.
+func mustParseTimeOfDay(value string) time.Duration {
+	timeOfDay, _ := ParseTimeOfDay(value)
+	return timeOfDay
+}
@@ -0,0 +1,218 @@
+package Lightshow
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createTestScheduleManager returns a manager on the clock that records the programs it applies.
+func createTestScheduleManager(t *testing.T, schedule Schedule, clock Clock) (*ScheduleManager, *[]string) {
+	if err := schedule.validate(); err != nil {
+		t.Fatalf("the schedule is invalid: %s", err)
+	}
+
+	var applied []string
+	manager := &ScheduleManager{
+		schedule: schedule,
+		apply: func(program ShowProgram) {
+			applied = append(applied, program.Start)
+		},
+		clock:   clock,
+		changed: make(chan struct{}, 1),
+		lock:    &sync.Mutex{},
+	}
+
+	return manager, &applied
+}
+
+func createTestPrograms(starts ...string) []ShowProgram {
+	programs := make([]ShowProgram, len(starts))
+	for i, start := range starts {
+		programs[i] = ShowProgram{Start: start, Mood: Party, Brightness: 100}
+	}
+
+	return programs
+}
+
+func getStarts(programs []ShowProgram) []string {
+	starts := make([]string, len(programs))
+	for i, program := range programs {
+		starts[i] = program.Start
+	}
+
+	return starts
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		programs []ShowProgram
+		expected []string //The sorted starts, nil if the schedule is invalid
+	}{
+		{"empty", nil, []string{}},
+		{"sorted by start", createTestPrograms("23:00", "02:00", "20:00"), []string{"02:00", "20:00", "23:00"}},
+		{"formatted", createTestPrograms(" 9:5", "21:30"), []string{"09:05", "21:30"}},
+		{"duplicate start", createTestPrograms("22:00", "20:00", "22:00"), nil},
+		{"duplicate start in another format", createTestPrograms("09:00", "9:00"), nil},
+		{"invalid time", createTestPrograms("24:00"), nil},
+		{"invalid mood", []ShowProgram{{Start: "20:00", Mood: Party + 1}}, nil},
+		{"invalid brightness", []ShowProgram{{Start: "20:00", Brightness: 101}}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule := Schedule{Enabled: true, Programs: test.programs}
+			err := schedule.validate()
+
+			if test.expected == nil {
+				if err == nil {
+					t.Errorf("accepted the programs %v", getStarts(schedule.Programs))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("validation failed: %s", err)
+			}
+			if starts := getStarts(schedule.Programs); !slices.Equal(starts, test.expected) {
+				t.Errorf("programs start at %v, expected %v", starts, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetCurrentProgram(t *testing.T) {
+	day := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+	at := func(days int, timeOfDay string) time.Time {
+		return day.AddDate(0, 0, days).Add(mustParseTimeOfDay(timeOfDay))
+	}
+
+	tests := []struct {
+		name      string
+		schedule  Schedule
+		now       time.Time
+		expected  string //The start of the current program, empty if there is none
+		nextStart time.Time
+	}{
+		{
+			name:      "within the night",
+			schedule:  Schedule{Enabled: true, Programs: createTestPrograms("20:00", "23:00", "02:00")},
+			now:       at(0, "21:15"),
+			expected:  "20:00",
+			nextStart: at(0, "23:00"),
+		},
+		{
+			name:      "next start after midnight",
+			schedule:  Schedule{Enabled: true, Programs: createTestPrograms("20:00", "23:00", "02:00")},
+			now:       at(0, "23:30"),
+			expected:  "23:00",
+			nextStart: at(1, "02:00"),
+		},
+		{
+			name:      "last program runs past midnight",
+			schedule:  Schedule{Enabled: true, Programs: createTestPrograms("20:00", "23:00")},
+			now:       at(1, "01:00"),
+			expected:  "23:00",
+			nextStart: at(1, "20:00"),
+		},
+		{
+			name:      "at the start",
+			schedule:  Schedule{Enabled: true, Programs: createTestPrograms("20:00", "23:00", "02:00")},
+			now:       at(0, "02:00"),
+			expected:  "02:00",
+			nextStart: at(0, "20:00"),
+		},
+		{
+			name:      "single program",
+			schedule:  Schedule{Enabled: true, Programs: createTestPrograms("20:00")},
+			now:       at(0, "21:00"),
+			expected:  "20:00",
+			nextStart: at(1, "20:00"),
+		},
+		{
+			name:     "disabled",
+			schedule: Schedule{Enabled: false, Programs: createTestPrograms("20:00")},
+			now:      at(0, "21:00"),
+		},
+		{
+			name:     "empty",
+			schedule: Schedule{Enabled: true},
+			now:      at(0, "21:00"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager, _ := createTestScheduleManager(t, test.schedule, &simulatedClock{})
+
+			program, nextStart, ok := manager.getCurrentProgram(test.now)
+			if !ok {
+				if test.expected != "" {
+					t.Errorf("no program is running, expected the one starting at %s", test.expected)
+				}
+				return
+			}
+
+			if program.Start != test.expected || !nextStart.Equal(test.nextStart) {
+				t.Errorf("the program starting at %s runs until %v, expected the one starting at %s until %v",
+					program.Start, nextStart, test.expected, test.nextStart)
+			}
+		})
+	}
+}
+
+func TestApplyDueProgram(t *testing.T) {
+	day := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		starts   []string
+		checks   []time.Duration //The times the scheduler wakes up at, since midnight of the first day
+		expected []string
+	}{
+		{
+			name:     "programs switch at their start",
+			starts:   []string{"20:00", "23:00"},
+			checks:   []time.Duration{21 * time.Hour, 22 * time.Hour, 23 * time.Hour, 44 * time.Hour},
+			expected: []string{"20:00", "23:00", "20:00"},
+		},
+		{
+			//Changes by hand last until the program starts again on the next day
+			name:     "single program every day",
+			starts:   []string{"20:00"},
+			checks:   []time.Duration{21 * time.Hour, 23 * time.Hour, 44 * time.Hour, 45 * time.Hour, 68 * time.Hour},
+			expected: []string{"20:00", "20:00", "20:00"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &simulatedClock{}
+			schedule := Schedule{Enabled: true, Programs: createTestPrograms(test.starts...)}
+			manager, applied := createTestScheduleManager(t, schedule, clock)
+
+			for _, check := range test.checks {
+				clock.now = day.Add(check)
+				if _, ok := manager.applyDueProgram(); !ok {
+					t.Fatalf("no program is due at %v", clock.now)
+				}
+			}
+
+			if !slices.Equal(*applied, test.expected) {
+				t.Errorf("applied the programs %v, expected %v", *applied, test.expected)
+			}
+		})
+	}
+}
+
+func TestApplyDueProgramWaitsForTheNextStart(t *testing.T) {
+	clock := &simulatedClock{now: time.Date(2024, 3, 9, 21, 30, 0, 0, time.UTC)}
+	schedule := Schedule{Enabled: true, Programs: createTestPrograms("20:00", "23:00")}
+	manager, _ := createTestScheduleManager(t, schedule, clock)
+
+	if wait, ok := manager.applyDueProgram(); !ok || wait != 90*time.Minute {
+		t.Errorf("waits %v (%t) for the next program, expected %v", wait, ok, 90*time.Minute)
+	}
+}
@@ -0,0 +1,108 @@
+package Lightshow
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+)
+
+const (
+	DefaultWeight  = 1.0
+	MaxWeight      = 10.0
+	recencyHorizon = 8   //Picks after which an item gets the full least recently used bias
+	recencyBias    = 1.0 //Extra weight of an item that wasn't picked within the horizon, relative to its weight
+)
+
+// selectable is an item auto mode picks by its weight.
+type selectable interface {
+	selectionId() uint32
+	GetWeight() float64
+}
+
+// selectionHistory remembers when items were picked, so auto mode doesn't repeat them right away
+// and prefers the ones it didn't pick for a while.
+type selectionHistory struct {
+	lastPicks map[uint32]int //The pick number each item was last picked at
+	picks     int
+	window    int //Number of picks an item isn't picked again after it was, as long as there are other items
+}
+
+func createSelectionHistory(window int) *selectionHistory {
+	return &selectionHistory{
+		lastPicks: make(map[uint32]int),
+		window:    window,
+	}
+}
+
+// getAge returns how many picks ago the item was picked, the horizon if it wasn't picked within it.
+func (history *selectionHistory) getAge(id uint32) int {
+	lastPick, ok := history.lastPicks[id]
+	if !ok {
+		return recencyHorizon
+	}
+
+	return min(history.picks-lastPick-1, recencyHorizon)
+}
+
+func (history *selectionHistory) remember(id uint32) {
+	history.lastPicks[id] = history.picks
+	history.picks++
+}
+
+// pickWeighted randomly picks one of the candidates by its weight and remembers the pick. Candidates picked within
+// the window of the history are left out unless nothing else is left, the longer ago a candidate was picked,
+// the higher its chance. There must be at least one candidate.
+func pickWeighted[T selectable](history *selectionHistory, candidates []T, random *rand.Rand) T {
+	//The order of the candidates often comes from a map, sorting keeps the picks reproducible for a seeded random source
+	sorted := slices.Clone(candidates)
+	slices.SortFunc(sorted, func(a, b T) int {
+		return cmp.Compare(a.selectionId(), b.selectionId())
+	})
+
+	weights := make([]float64, len(sorted))
+	total := 0.0
+	for i, candidate := range sorted {
+		age := history.getAge(candidate.selectionId())
+		if age < history.window {
+			continue
+		}
+
+		weights[i] = candidate.GetWeight() * (1 + recencyBias*float64(age)/recencyHorizon)
+		total += weights[i]
+	}
+
+	//Fall back to the plain weights if only recent picks are left
+	if total <= 0 {
+		for i, candidate := range sorted {
+			weights[i] = candidate.GetWeight()
+			total += weights[i]
+		}
+	}
+
+	picked := sorted[len(sorted)-1]
+	target := random.Float64() * total
+	for i, weight := range weights {
+		if target < weight {
+			picked = sorted[i]
+			break
+		}
+		target -= weight
+	}
+
+	history.remember(picked.selectionId())
+	return picked
+}
+
+// getWeight returns the weight, the default one if it wasn't set.
+func getWeight(weight float64) float64 {
+	if weight <= 0 {
+		return DefaultWeight
+	}
+
+	return weight
+}
+
+// ValidateWeight returns whether the weight can be set on a palette or animation, 0 sets the default weight.
+func ValidateWeight(weight float64) bool {
+	return weight >= 0 && weight <= MaxWeight
+}
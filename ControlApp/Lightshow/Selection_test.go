@@ -0,0 +1,110 @@
+package Lightshow
+
+import (
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+type testItem struct {
+	id     uint32
+	weight float64
+}
+
+func (item testItem) selectionId() uint32 {
+	return item.id
+}
+
+func (item testItem) GetWeight() float64 {
+	return item.weight
+}
+
+// getShare picks from the candidates and returns how often each of them was picked, relative to all picks.
+func getShare(history *selectionHistory, candidates []testItem, picks int, random *rand.Rand) map[uint32]float64 {
+	share := make(map[uint32]float64)
+	for i := 0; i < picks; i++ {
+		share[pickWeighted(history, candidates, random).id] += 1 / float64(picks)
+	}
+
+	return share
+}
+
+func TestPickWeightedAvoidsRepeats(t *testing.T) {
+	candidates := []testItem{{1, 1}, {2, 1}, {3, 1}, {4, 1}}
+	history := createSelectionHistory(2)
+	random := rand.New(rand.NewSource(7))
+
+	var picks []uint32
+	for i := 0; i < 200; i++ {
+		picked := pickWeighted(history, candidates, random).id
+		if recent := picks[max(len(picks)-2, 0):]; slices.Contains(recent, picked) {
+			t.Fatalf("picked %d again after %v", picked, recent)
+		}
+		picks = append(picks, picked)
+	}
+}
+
+func TestPickWeightedFallsBackToRecentPicks(t *testing.T) {
+	//Every candidate is within the window, so the plain weights decide
+	candidates := []testItem{{1, 9}, {2, 1}}
+	history := createSelectionHistory(recencyHorizon + 1)
+
+	share := getShare(history, candidates, 2000, rand.New(rand.NewSource(7)))
+	if math.Abs(share[1]-0.9) > 0.03 {
+		t.Errorf("picked the heavy candidate in %.2f of the picks, expected 0.9", share[1])
+	}
+}
+
+func TestPickWeightedPrefersLeastRecentlyUsed(t *testing.T) {
+	candidates := []testItem{{1, 1}, {2, 1}}
+	random := rand.New(rand.NewSource(7))
+
+	//The candidate picked last keeps its weight, the other one gets the full bias
+	expected := (1 + recencyBias) / (2 + recencyBias)
+	share := 0.0
+	const trials = 2000
+	for i := 0; i < trials; i++ {
+		history := createSelectionHistory(0)
+		history.remember(1)
+		if pickWeighted(history, candidates, random).id == 2 {
+			share += 1.0 / trials
+		}
+	}
+
+	if math.Abs(share-expected) > 0.03 {
+		t.Errorf("picked the least recently used candidate in %.2f of the picks, expected %.2f", share, expected)
+	}
+}
+
+func TestPickWeightedIgnoresCandidateOrder(t *testing.T) {
+	candidates := []testItem{{1, 1}, {2, 2}, {3, 3}}
+	reversed := slices.Clone(candidates)
+	slices.Reverse(reversed)
+
+	first := getShare(createSelectionHistory(1), candidates, 50, rand.New(rand.NewSource(7)))
+	second := getShare(createSelectionHistory(1), reversed, 50, rand.New(rand.NewSource(7)))
+	if !maps.Equal(first, second) {
+		t.Errorf("picked %v, but %v from the reversed candidates", first, second)
+	}
+}
+
+func TestGetWeight(t *testing.T) {
+	tests := []struct {
+		name     string
+		weight   float64
+		expected float64
+	}{
+		{"unset", 0, DefaultWeight},
+		{"set", 2.5, 2.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := getWeight(test.weight); result != test.expected {
+				t.Errorf("weight is %g, expected %g", result, test.expected)
+			}
+		})
+	}
+}
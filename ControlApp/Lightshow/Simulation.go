@@ -0,0 +1,158 @@
+package Lightshow
+
+import (
+	"ControlApp/BoxiBus"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// SimulationScript describes the music auto mode is simulated with.
+type SimulationScript struct {
+	Beats    []time.Duration //When beats are detected, since the start of the simulation
+	Dirty    []time.Duration //When the lightshow is marked dirty, since the start of the simulation
+	Duration time.Duration
+	Seed     int64 //Seeds the random decisions, the same script and seed always lead to the same calls
+}
+
+type SimulatedCallType uint8
+
+const (
+	LightingCall SimulatedCallType = iota
+	AnimationCall
+	BeatCall
+)
+
+// SimulatedCall is a call auto mode made during the simulation.
+type SimulatedCall struct {
+	At        time.Duration //Since the start of the simulation
+	Type      SimulatedCallType
+	Lighting  LightingInstruction   //Set for lighting calls
+	Animation AnimationsInstruction //Set for animation calls
+}
+
+// Character returns the character of the applied lighting or animation.
+func (call SimulatedCall) Character() ModeCharacter {
+	switch call.Type {
+	case LightingCall:
+		return call.Lighting.character
+	case AnimationCall:
+		return call.Animation.Character
+	}
+
+	return Unknown
+}
+
+type simulatedClock struct {
+	now time.Time
+}
+
+func (clock *simulatedClock) Now() time.Time {
+	return clock.now
+}
+
+// simulationManager records the calls of auto mode instead of passing them to the hardware.
+type simulationManager struct {
+	clock      *simulatedClock
+	start      time.Time
+	calls      []SimulatedCall
+	animations *AnimationManager
+	palettes   *PaletteManager
+}
+
+func (manager *simulationManager) record(call SimulatedCall) {
+	call.At = manager.clock.now.Sub(manager.start)
+	manager.calls = append(manager.calls, call)
+}
+
+func (manager *simulationManager) applyLighting(instruction LightingInstruction) {
+	manager.record(SimulatedCall{Type: LightingCall, Lighting: instruction})
+}
+
+func (manager *simulationManager) applyAnimation(instruction AnimationsInstruction) {
+	manager.record(SimulatedCall{Type: AnimationCall, Animation: instruction})
+}
+
+func (manager *simulationManager) triggerBeat() {
+	manager.record(SimulatedCall{Type: BeatCall})
+}
+
+// getBeats isn't used, the simulation passes the beats to auto mode itself.
+func (manager *simulationManager) getBeats() <-chan time.Time {
+	return nil
+}
+
+// SupportsLightingMode pretends the firmware knows every mode, so the simulation covers all of them.
+func (manager *simulationManager) SupportsLightingMode(mode BoxiBus.LightingModeId) bool {
+	return true
+}
+
+// SupportsPerBoxiLighting pretends the firmware runs a separate program on each Boxi, so the simulation covers it.
+func (manager *simulationManager) SupportsPerBoxiLighting() bool {
+	return true
+}
+
+func (manager *simulationManager) GetAnimations() *AnimationManager {
+	return manager.animations
+}
+
+func (manager *simulationManager) GetPalettes() *PaletteManager {
+	return manager.palettes
+}
+
+// SimulateAutoMode runs auto mode on simulated time through the script and returns all calls it made.
+// The simulation jumps from event to event, so it takes no longer than the decisions themselves.
+func SimulateAutoMode(configuration AutoModeConfiguration, animations *AnimationManager, palettes *PaletteManager, script SimulationScript) []SimulatedCall {
+	start := time.Unix(0, 0)
+	clock := &simulatedClock{now: start}
+	manager := &simulationManager{clock: clock, start: start, animations: animations, palettes: palettes}
+	context := createAutoModeContext(manager, configuration, clock, rand.New(rand.NewSource(script.Seed)))
+
+	beats := slices.Clone(script.Beats)
+	slices.Sort(beats)
+	dirty := slices.Clone(script.Dirty)
+	slices.Sort(dirty)
+
+	for {
+		//Find the next event, beats and changes go before deadlines at the same time
+		next := script.Duration
+		deadline, hasDeadline := context.getNextDeadline()
+		if hasDeadline {
+			next = min(next, max(deadline.Sub(start), clock.now.Sub(start)))
+		}
+		if len(dirty) > 0 {
+			next = min(next, dirty[0])
+		}
+		if len(beats) > 0 {
+			next = min(next, beats[0])
+		}
+
+		if next >= script.Duration {
+			return manager.calls
+		}
+
+		clock.now = start.Add(next)
+
+		switch {
+		case len(beats) > 0 && beats[0] == next:
+			beats = beats[1:]
+			context.handle(&clock.now, false)
+		case len(dirty) > 0 && dirty[0] == next:
+			dirty = dirty[1:]
+			context.handle(nil, true)
+		default:
+			context.handle(nil, false)
+		}
+	}
+}
+
+// CreateBeatTimeline returns the beats of music with a steady tempo, from the start until the end.
+func CreateBeatTimeline(bpm float64, start time.Duration, end time.Duration) []time.Duration {
+	var beats []time.Duration
+	period := time.Duration(float64(time.Minute) / bpm)
+	for at := start; at < end; at += period {
+		beats = append(beats, at)
+	}
+
+	return beats
+}
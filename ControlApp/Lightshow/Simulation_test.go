@@ -0,0 +1,219 @@
+package Lightshow
+
+import (
+	"ControlApp/BoxiBus"
+	"ControlApp/Display"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createTestConfiguration returns a configuration without strobes whose switches happen after a fixed number of beats.
+func createTestConfiguration() AutoModeConfiguration {
+	return AutoModeConfiguration{
+		Mood:                    Regular,
+		HueShiftChance:          3,
+		FadeToColorCycles:       700,
+		PaletteFadeCycles:       500,
+		FlashFadeoutSpeed:       30,
+		HueFlashFadeoutSpeed:    15,
+		StrobeFrequency:         2,
+		FlashTargetBrightness:   20,
+		FlashHueShift:           1,
+		RainbowCycleCycles:      1300,
+		BreathingCycles:         520,
+		BreathingMinBrightness:  25,
+		BeatsPerBar:             4,
+		BarsPerPhrase:           16,
+		MinTimeBetweenBeats:     360 * time.Millisecond,
+		LightingCalmModeBoring:  30 * time.Second,
+		AnimationCalmModeBoring: 16 * time.Second,
+		LightingModeTiming: map[ModeCharacter]TimingConstraint{
+			Calm:     {8, 9, 5 * time.Second},
+			Rhythmic: {6, 7, 3 * time.Second},
+		},
+		AnimationModeTiming: map[ModeCharacter]TimingConstraint{
+			Calm:     {32, 33, 8 * time.Second},
+			Rhythmic: {12, 13, 4 * time.Second},
+		},
+	}
+}
+
+func createTestAnimations() *AnimationManager {
+	animations := map[Display.AnimationId]Animation{
+		1: {Id: 1, Name: "Happy", Mood: Happy},
+		2: {Id: 2, Name: "Moody", Mood: Moody},
+		3: {Id: 3, Name: "Regular", Mood: Regular},
+	}
+
+	return &AnimationManager{animations: animations, accessLock: &sync.Mutex{}}
+}
+
+// simulate runs auto mode with the default palettes through the script.
+func simulate(configuration AutoModeConfiguration, script SimulationScript) []SimulatedCall {
+	palettes := &PaletteManager{palettes: map[uint32]Palette{}, accessLock: &sync.Mutex{}}
+	return SimulateAutoMode(configuration, createTestAnimations(), palettes, script)
+}
+
+// isAppliedOnBeat returns whether the lighting set by the block waits for the next beat.
+func isAppliedOnBeat(block BoxiBus.MessageBlock) bool {
+	applyOnBeat := false
+	for _, message := range block {
+		if message.Field() == BoxiBus.LightingApply {
+			applyOnBeat = message.Payload()[0] != 0
+		}
+	}
+
+	return applyOnBeat
+}
+
+type expectedLighting struct {
+	at          time.Duration
+	character   ModeCharacter
+	applyOnBeat bool
+}
+
+// TestSimulateAutoModeLighting checks when auto mode switches the lighting and how the switch is picked.
+// Calm lighting is applied right away, the first beat after calm mode too, other beats wait for the next beat.
+func TestSimulateAutoModeLighting(t *testing.T) {
+	aligned := func(beatsPerBar int, barsPerPhrase int) func(*AutoModeConfiguration) {
+		return func(configuration *AutoModeConfiguration) {
+			configuration.PhraseAligned = true
+			configuration.BeatsPerBar = beatsPerBar
+			configuration.BarsPerPhrase = barsPerPhrase
+		}
+	}
+
+	tests := []struct {
+		name      string
+		configure func(*AutoModeConfiguration)
+		beats     []time.Duration
+		duration  time.Duration
+		expected  []expectedLighting
+	}{
+		{
+			name:     "first beat and later beats",
+			beats:    CreateBeatTimeline(120, 0, 10*time.Second),
+			duration: 10 * time.Second,
+			expected: []expectedLighting{
+				{0, Rhythmic, true},
+				{3 * time.Second, Rhythmic, true},
+				{6 * time.Second, Rhythmic, true},
+				{9 * time.Second, Rhythmic, true},
+			},
+		},
+		{
+			//The tempo tracker fills in 4 beats after the last one at 9.5s, the dead time runs from the last filled beat
+			name:     "dead time switches to calm",
+			beats:    CreateBeatTimeline(120, 0, 10*time.Second),
+			duration: 20 * time.Second,
+			expected: []expectedLighting{
+				{0, Rhythmic, true},
+				{3 * time.Second, Rhythmic, true},
+				{6 * time.Second, Rhythmic, true},
+				{9 * time.Second, Rhythmic, true},
+				{14577 * time.Millisecond, Calm, false},
+			},
+		},
+		{
+			name:     "boring calm switches away",
+			duration: 95 * time.Second,
+			expected: []expectedLighting{
+				{30001 * time.Millisecond, Calm, false},
+				{60002 * time.Millisecond, Calm, false},
+				{90003 * time.Millisecond, Calm, false},
+			},
+		},
+		{
+			name:     "first beat after calm",
+			beats:    CreateBeatTimeline(120, 40*time.Second, 47*time.Second),
+			duration: 47 * time.Second,
+			expected: []expectedLighting{
+				{30001 * time.Millisecond, Calm, false},
+				{40 * time.Second, Rhythmic, false},
+				{43 * time.Second, Rhythmic, true},
+				{46 * time.Second, Rhythmic, true},
+			},
+		},
+		{
+			//Phrases of 8 beats are longer than the 7 beats rhythmic lighting may last, so the switches align to bars
+			name:      "aligned to bars",
+			configure: aligned(4, 2),
+			beats:     CreateBeatTimeline(120, 0, 13*time.Second),
+			duration:  13 * time.Second,
+			expected: []expectedLighting{
+				{0, Rhythmic, true},
+				{4 * time.Second, Rhythmic, true},
+				{8 * time.Second, Rhythmic, true},
+				{12 * time.Second, Rhythmic, true},
+			},
+		},
+		{
+			name:      "aligned to phrases",
+			configure: aligned(7, 1),
+			beats:     CreateBeatTimeline(120, 0, 13*time.Second),
+			duration:  13 * time.Second,
+			expected: []expectedLighting{
+				{0, Rhythmic, true},
+				{3500 * time.Millisecond, Rhythmic, true},
+				{7 * time.Second, Rhythmic, true},
+				{10500 * time.Millisecond, Rhythmic, true},
+			},
+		},
+		{
+			//The phrase restarts with the first beat after calm mode
+			name:      "aligned after calm",
+			configure: aligned(4, 2),
+			beats:     CreateBeatTimeline(120, 40*time.Second, 49*time.Second),
+			duration:  49 * time.Second,
+			expected: []expectedLighting{
+				{30001 * time.Millisecond, Calm, false},
+				{40 * time.Second, Rhythmic, false},
+				{44 * time.Second, Rhythmic, true},
+				{48 * time.Second, Rhythmic, true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configuration := createTestConfiguration()
+			if test.configure != nil {
+				test.configure(&configuration)
+			}
+
+			script := SimulationScript{Beats: test.beats, Duration: test.duration, Seed: 7}
+			var lighting []expectedLighting
+			for _, call := range simulate(configuration, script) {
+				if call.Type == LightingCall {
+					lighting = append(lighting, expectedLighting{call.At, call.Character(), isAppliedOnBeat(call.Lighting.MessageBlock)})
+				}
+			}
+
+			if !slices.Equal(lighting, test.expected) {
+				t.Errorf("switched lighting %v, expected %v", lighting, test.expected)
+			}
+		})
+	}
+}
+
+// TestSimulateAutoModeIsReproducible checks that the same script and seed lead to the same calls.
+func TestSimulateAutoModeIsReproducible(t *testing.T) {
+	configuration := createTestConfiguration()
+	configuration.Mood = Party
+	configuration.StrobeChance = 3
+	script := SimulationScript{
+		Beats:    CreateBeatTimeline(128, 0, 2*time.Minute),
+		Dirty:    []time.Duration{12 * time.Second},
+		Duration: 3 * time.Minute,
+		Seed:     7,
+	}
+
+	first := simulate(configuration, script)
+	second := simulate(configuration, script)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("simulated %d and %d different calls with the same seed", len(first), len(second))
+	}
+}
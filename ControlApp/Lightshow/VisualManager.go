@@ -1,16 +1,24 @@
 package Lightshow
 
 import (
+	"ControlApp/BeatDetection"
 	"ControlApp/BoxiBus"
 	"ControlApp/Display"
 	"ControlApp/Infrastructure"
+	"context"
+	"log"
 	"sync"
+	"time"
 )
 
+// How often the rendered lighting is sent to outputs that don't go through the Arduino
+const renderInterval = 25 * time.Millisecond
+
 type VisualManager struct {
 	autoContext                   *AutoModeContext
 	animations                    *AnimationManager
 	palettes                      *PaletteManager
+	schedule                      *ScheduleManager
 	hardwareManager               Infrastructure.HardwareInterface
 	lightingIsOverwritten         bool
 	animationOverwrite            *AnimationsInstruction
@@ -19,17 +27,27 @@ type VisualManager struct {
 	lightingCurrentAutoSelection  LightingInstruction
 	animationCurrentAutoSelection AnimationsInstruction
 	textValues                    TextsInstruction
+	lightingPendingMode           *BoxiBus.LightingModeId
+	lightingConfirmed             bool
+	renderer                      *Renderer
 	accessLock                    *sync.Mutex
 }
 
 func CreateVisualManager(hardwareManager Infrastructure.HardwareInterface) *VisualManager {
-	visual := VisualManager{hardwareManager: hardwareManager, accessLock: &sync.Mutex{}, brightnessValue: 1, internalLedsEnabled: true}
+	visual := VisualManager{hardwareManager: hardwareManager, accessLock: &sync.Mutex{}, brightnessValue: 1, internalLedsEnabled: true,
+		renderer: CreateRenderer()}
 	visual.animations = LoadAnimations()
 	visual.palettes = LoadPalettes()
-	visual.autoContext = CreateAutoMode(&visual, load())
+	visual.autoContext = CreateAutoMode(&visual, LoadAutoModeConfiguration())
+	if err := visual.autoContext.Start(context.Background()); err != nil {
+		log.Fatalf("Error starting auto mode: %s", err)
+	}
+	visual.schedule = LoadSchedule(visual.applyShowProgram)
 
 	// Sync animations when they get uploaded
 	go visual.watchForAnimationUploads()
+	go visual.renderLighting()
+	go visual.schedule.run()
 
 	return &visual
 }
@@ -39,6 +57,23 @@ type LightingInstruction struct {
 	character ModeCharacter
 }
 
+// CreateLightingInstruction encodes a validated lighting mode into an instruction for both Boxis.
+func CreateLightingInstruction(mode LightingMode) LightingInstruction {
+	return LightingInstruction{mode.Encode(), getLightingModeCharacter(mode.Id())}
+}
+
+// CreateLightingInstructionPerBoxi encodes a separate validated lighting mode for each Boxi into an instruction.
+// The instruction takes the livelier character of both modes.
+func CreateLightingInstructionPerBoxi(boxi1 LightingMode, boxi2 LightingMode) LightingInstruction {
+	block := BoxiBus.CreateLightingPerBoxi(boxi1.Encode(), boxi2.Encode())
+	character := getLightingModeCharacter(boxi1.Id())
+	if boxi2Character := getLightingModeCharacter(boxi2.Id()); character == Unknown || boxi2Character != Unknown && boxi2Character > character {
+		character = boxi2Character
+	}
+
+	return LightingInstruction{block, character}
+}
+
 type AnimationInstruction struct {
 	Animation Display.AnimationId
 	Displays  []Display.ServerDisplay
@@ -65,7 +100,64 @@ func (manager *VisualManager) applyLighting(instruction LightingInstruction) {
 		return
 	}
 
-	manager.hardwareManager.SendLightingInstruction(instruction.MessageBlock)
+	manager.sendLighting(instruction.MessageBlock)
+}
+
+// sendLighting sends the block to the hardware and marks it pending until the Arduino confirms it. Requires the access lock.
+func (manager *VisualManager) sendLighting(block BoxiBus.MessageBlock) {
+	mode, ok := block.GetLightingMode()
+	if ok {
+		manager.lightingPendingMode = nil
+		manager.lightingConfirmed = false
+
+		//Firmware without telemetry never confirms the lighting
+		if manager.hardwareManager.GetConnectionState().HasTelemetry() {
+			manager.lightingPendingMode = &mode
+		}
+	}
+
+	manager.renderer.Write(block)
+	manager.hardwareManager.SendLightingInstruction(block)
+}
+
+// renderLighting follows the lighting of the Arduino and sends the colors to the outputs that don't go through it.
+func (manager *VisualManager) renderLighting() {
+	lastFrame := time.Now()
+	for now := range time.Tick(renderInterval) {
+		colors := manager.renderer.Advance(now.Sub(lastFrame))
+		lastFrame = now
+		manager.hardwareManager.SendRenderedLighting(colors[0], colors[1])
+	}
+}
+
+// LightingApplied gets called by the hardware when the Arduino reports a lighting mode taking effect.
+func (manager *VisualManager) LightingApplied(mode BoxiBus.LightingModeId) {
+	manager.accessLock.Lock()
+	defer manager.accessLock.Unlock()
+
+	if manager.lightingPendingMode != nil && *manager.lightingPendingMode == mode {
+		manager.lightingPendingMode = nil
+		manager.lightingConfirmed = true
+	}
+}
+
+// SupportsLightingMode returns whether the firmware of the Arduino knows the lighting mode.
+func (manager *VisualManager) SupportsLightingMode(mode BoxiBus.LightingModeId) bool {
+	return manager.hardwareManager.GetConnectionState().SupportsLightingMode(mode)
+}
+
+// SupportsPerBoxiLighting returns whether the firmware of the Arduino runs a separate lighting program for each Boxi.
+func (manager *VisualManager) SupportsPerBoxiLighting() bool {
+	return manager.hardwareManager.GetConnectionState().SupportsPerBoxiLighting()
+}
+
+// IsLightingConfirmed returns whether the Arduino confirmed that the last lighting instruction took effect.
+// It stays false for firmware that doesn't send telemetry, like lightshow_v3.
+func (manager *VisualManager) IsLightingConfirmed() bool {
+	manager.accessLock.Lock()
+	defer manager.accessLock.Unlock()
+
+	return manager.lightingConfirmed
 }
 
 func (manager *VisualManager) applyAnimation(instruction AnimationsInstruction) {
@@ -127,11 +219,12 @@ func (manager *VisualManager) applyAnimation(instruction AnimationsInstruction)
 }
 
 func (manager *VisualManager) triggerBeat() {
+	manager.renderer.Beat()
 	manager.hardwareManager.SendBeatToDisplay(false)
 }
 
-func (manager *VisualManager) getBeatState() bool {
-	return manager.hardwareManager.GetBeatState()
+func (manager *VisualManager) getBeats() <-chan time.Time {
+	return manager.hardwareManager.GetBeats()
 }
 
 func (manager *VisualManager) GetAnimations() *AnimationManager {
@@ -142,15 +235,25 @@ func (manager *VisualManager) GetPalettes() *PaletteManager {
 	return manager.palettes
 }
 
+// GetRenderer returns the renderer that knows the colors the Boxis are showing.
+func (manager *VisualManager) GetRenderer() *Renderer {
+	return manager.renderer
+}
+
+// GetTempo returns the tracker that follows the tempo of the music.
+func (manager *VisualManager) GetTempo() *BeatDetection.TempoTracker {
+	return manager.autoContext.tempo
+}
+
 func (manager *VisualManager) SetLightingOverwrite(instruction *LightingInstruction) {
 	manager.accessLock.Lock()
 	defer manager.accessLock.Unlock()
 
 	manager.lightingIsOverwritten = instruction != nil
 	if instruction == nil {
-		manager.hardwareManager.SendLightingInstruction(manager.lightingCurrentAutoSelection.MessageBlock)
+		manager.sendLighting(manager.lightingCurrentAutoSelection.MessageBlock)
 	} else {
-		manager.hardwareManager.SendLightingInstruction(instruction.MessageBlock)
+		manager.sendLighting(instruction.MessageBlock)
 	}
 }
 
@@ -227,8 +330,28 @@ func (manager *VisualManager) ImportAnimation(path string, name string, mood Lig
 	return manager.animations.ImportAnimation(path, name, mood, splitVideo, isNsfw)
 }
 
-func (manager *VisualManager) GetConfiguration() *AutoModeConfiguration {
-	return &manager.autoContext.Configuration
+// GetAutoMode returns auto mode, which picks the lighting and animations while they aren't overwritten.
+func (manager *VisualManager) GetAutoMode() *AutoModeContext {
+	return manager.autoContext
+}
+
+// GetConfiguration returns a copy of the auto mode configuration.
+func (manager *VisualManager) GetConfiguration() AutoModeConfiguration {
+	return manager.autoContext.GetConfiguration()
+}
+
+// GetSchedule returns the schedule of the show programs.
+func (manager *VisualManager) GetSchedule() *ScheduleManager {
+	return manager.schedule
+}
+
+// applyShowProgram switches to the mood and brightness of a show program.
+func (manager *VisualManager) applyShowProgram(program ShowProgram) {
+	manager.UpdateConfiguration(func(configuration *AutoModeConfiguration) {
+		configuration.Mood = program.Mood
+		configuration.AllowNsfw = program.AllowNsfw
+	}, true)
+	manager.SetBrightness(float64(program.Brightness) / 100)
 }
 
 func (manager *VisualManager) GetBrightness() float64 {
@@ -239,13 +362,16 @@ func (manager *VisualManager) GetInternalLeds() bool {
 	return manager.internalLedsEnabled
 }
 
-func (manager *VisualManager) StoreConfiguration(markAsDirty bool) {
-	manager.GetConfiguration().Store()
-	manager.autoContext.isDirty = markAsDirty
+// UpdateConfiguration changes and stores the auto mode configuration.
+func (manager *VisualManager) UpdateConfiguration(change func(configuration *AutoModeConfiguration), markAsDirty bool) {
+	manager.autoContext.UpdateConfiguration(change)
+	if markAsDirty {
+		manager.autoContext.markDirty()
+	}
 }
 
 func (manager *VisualManager) MarkLightshowAsDirty() {
-	manager.autoContext.isDirty = true
+	manager.autoContext.markDirty()
 }
 
 func (manager *VisualManager) watchForAnimationUploads() {
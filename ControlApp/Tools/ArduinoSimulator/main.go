@@ -0,0 +1,62 @@
+package main
+
+import (
+	"ControlApp/BoxiBus"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Emulates the Arduino on a pseudo-terminal. Point the SerialDevice of Configuration/hardware.json
+// to the printed device path to run the ControlApp without the Boxi hardware.
+func main() {
+	beatInterval := flag.Duration("beat", 500*time.Millisecond, "interval of the simulated beat impulses, 0 disables them")
+	protocolVersion := flag.Uint("protocol", 6, "newest bus protocol version the simulated firmware understands, 1 behaves like lightshow_v3")
+	bitErrorRate := flag.Float64("ber", 0, "probability of every received bit being flipped")
+	flag.Parse()
+
+	simulator, err := BoxiBus.StartSimulator(byte(*protocolVersion), func(message BoxiBus.BusMessage, applied BoxiBus.LightingTarget, memory BoxiBus.ArduinoMemory) {
+		log.Printf("Received %s \n", message)
+		logActiveFieldSets(applied, memory)
+	})
+	if err != nil {
+		log.Fatalf("Error starting simulator: %s", err)
+	}
+	defer func() {
+		_ = simulator.Close()
+	}()
+
+	simulator.SetBitErrorRate(*bitErrorRate)
+	log.Printf("Arduino simulator listening on %s at any baud rate \n", simulator.DevicePath)
+
+	var beats <-chan time.Time
+	if *beatInterval > 0 {
+		ticker := time.NewTicker(*beatInterval)
+		defer ticker.Stop()
+		beats = ticker.C
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	for {
+		select {
+		case <-beats:
+			applied := simulator.Beat()
+			logActiveFieldSets(applied, simulator.GetMemory())
+		case <-interrupt:
+			return
+		}
+	}
+}
+
+func logActiveFieldSets(applied BoxiBus.LightingTarget, memory BoxiBus.ArduinoMemory) {
+	if applied&BoxiBus.TargetBoxi1 != 0 {
+		log.Printf("Lighting applied to Boxi 1, %s \n", memory.Active(BoxiBus.TargetBoxi1))
+	}
+	if applied&BoxiBus.TargetBoxi2 != 0 {
+		log.Printf("Lighting applied to Boxi 2, %s \n", memory.Active(BoxiBus.TargetBoxi2))
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"ControlApp/Lightshow"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Runs auto mode on simulated time and prints every lighting and animation it applies.
+// Uses the configuration, palettes and animations of the Configuration directory, so run it from the ControlApp directory.
+func main() {
+	bpm := flag.Float64("bpm", 128, "tempo of the simulated music")
+	duration := flag.Duration("duration", 5*time.Minute, "length of the simulation")
+	breaks := flag.String("breaks", "", "comma separated silent sections as start+length, e.g. 1m30s+20s,3m+45s")
+	dirty := flag.Duration("dirty", 12*time.Second, "when the lightshow is marked dirty like at startup, 0 disables it")
+	mood := flag.Int("mood", -1, "mood to simulate, the configured one if negative")
+	seed := flag.Int64("seed", 1, "seed of the random decisions")
+	showBeats := flag.Bool("beats", false, "also print the beats")
+	flag.Parse()
+
+	configuration := Lightshow.LoadAutoModeConfiguration()
+	if *mood >= 0 {
+		configuration.Mood = Lightshow.LightingMood(*mood)
+	}
+
+	silences, err := parseBreaks(*breaks)
+	if err != nil {
+		log.Fatalf("Invalid breaks: %s", err)
+	}
+
+	script := Lightshow.SimulationScript{Duration: *duration, Seed: *seed}
+	for _, beat := range Lightshow.CreateBeatTimeline(*bpm, 0, *duration) {
+		if !isSilent(beat, silences) {
+			script.Beats = append(script.Beats, beat)
+		}
+	}
+	if *dirty > 0 {
+		script.Dirty = []time.Duration{*dirty}
+	}
+
+	calls := Lightshow.SimulateAutoMode(configuration, Lightshow.LoadAnimations(), Lightshow.LoadPalettes(), script)
+	for _, call := range calls {
+		switch call.Type {
+		case Lightshow.LightingCall:
+			mode, _ := call.Lighting.GetLightingMode()
+			fmt.Printf("%10s  lighting   %-9s %s\n", call.At.Round(time.Millisecond), call.Character(), mode)
+		case Lightshow.AnimationCall:
+			var animations []string
+			for _, animation := range call.Animation.Animations {
+				animations = append(animations, fmt.Sprintf("%d on %v", animation.Animation, animation.Displays))
+			}
+			fmt.Printf("%10s  animation  %-9s %s\n", call.At.Round(time.Millisecond), call.Character(), strings.Join(animations, ", "))
+		case Lightshow.BeatCall:
+			if *showBeats {
+				fmt.Printf("%10s  beat\n", call.At.Round(time.Millisecond))
+			}
+		}
+	}
+}
+
+type silence struct {
+	start time.Duration
+	end   time.Duration
+}
+
+func parseBreaks(value string) ([]silence, error) {
+	var silences []silence
+	for _, part := range strings.Split(value, ",") {
+		if part == "" {
+			continue
+		}
+
+		start, length, found := strings.Cut(part, "+")
+		if !found {
+			return nil, fmt.Errorf("%q is missing the length", part)
+		}
+
+		startDuration, err := time.ParseDuration(start)
+		if err != nil {
+			return nil, err
+		}
+		lengthDuration, err := time.ParseDuration(length)
+		if err != nil {
+			return nil, err
+		}
+
+		silences = append(silences, silence{startDuration, startDuration + lengthDuration})
+	}
+
+	return silences, nil
+}
+
+func isSilent(at time.Duration, silences []silence) bool {
+	for _, section := range silences {
+		if at >= section.start && at < section.end {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"ControlApp/BoxiBus"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// Prints the lighting instructions contained in a capture of the bytes sent to the Arduino.
+// Reads the file given as argument or stdin, either as raw bytes or as a hex dump.
+func main() {
+	isHexDump := flag.Bool("hex", false, "the input is a hex dump instead of raw bytes")
+	flag.Parse()
+
+	input, err := readInput(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error reading capture: %s", err)
+	}
+
+	capture := input
+	if *isHexDump {
+		capture, err = parseHexDump(string(input))
+		if err != nil {
+			log.Fatalf("Error parsing hex dump: %s", err)
+		}
+	}
+
+	blocks, err := BoxiBus.DecodeBlocks(capture)
+	memory := BoxiBus.CreateArduinoMemory()
+	for i, block := range blocks {
+		fmt.Printf("Block %d:\n", i+1)
+		printBlock(block, memory)
+	}
+
+	if err != nil {
+		log.Fatalf("Capture couldn't be decoded completely: %s", err)
+	}
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(path)
+}
+
+// parseHexDump accepts whitespace or comma separated hex bytes, optionally prefixed with 0x.
+func parseHexDump(dump string) ([]byte, error) {
+	var result []byte
+	tokens := strings.FieldsFunc(dump, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\n' || r == '\r' || r == '\t'
+	})
+
+	for _, token := range tokens {
+		token = strings.TrimPrefix(strings.ToLower(token), "0x")
+		value, err := hex.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex token '%s'", token)
+		}
+
+		result = append(result, value...)
+	}
+
+	return result, nil
+}
+
+func printBlock(block BoxiBus.MessageBlock, memory *BoxiBus.ArduinoMemory) {
+	for _, message := range block {
+		payload := message.Payload()
+		applied, err := memory.Write(message)
+		if err != nil {
+			fmt.Printf("  Invalid message %s: %s\n", message, err)
+			continue
+		}
+
+		switch message.Field() {
+		case BoxiBus.StatusCode:
+			fmt.Printf("  Display status code 0x%02x for server %d\n", payload[0], payload[1])
+		case BoxiBus.EnableInternalLights:
+			fmt.Printf("  Internal LEDs enabled: %t\n", payload[0] != 0)
+		case BoxiBus.ProtocolVersion:
+			fmt.Printf("  Protocol v%d requested\n", payload[0])
+		case BoxiBus.LightingApply:
+			if applied != 0 {
+				printFieldSets("Lighting applied", applied, memory.Active)
+			} else {
+				printFieldSets("Lighting applied on next beat", memory.ApplyOnNextBeat, memory.Staged)
+				memory.Beat()
+			}
+		}
+	}
+}
+
+func printFieldSets(action string, target BoxiBus.LightingTarget, fieldSet func(boxi BoxiBus.LightingTarget) BoxiBus.LightingFieldSet) {
+	if target == BoxiBus.TargetBothBoxis && fieldSet(BoxiBus.TargetBoxi1) == fieldSet(BoxiBus.TargetBoxi2) {
+		fmt.Printf("  %s, %s\n", action, fieldSet(BoxiBus.TargetBoxi1))
+		return
+	}
+
+	if target&BoxiBus.TargetBoxi1 != 0 {
+		fmt.Printf("  %s to Boxi 1, %s\n", action, fieldSet(BoxiBus.TargetBoxi1))
+	}
+	if target&BoxiBus.TargetBoxi2 != 0 {
+		fmt.Printf("  %s to Boxi 2, %s\n", action, fieldSet(BoxiBus.TargetBoxi2))
+	}
+}
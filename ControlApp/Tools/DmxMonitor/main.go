@@ -0,0 +1,56 @@
+package main
+
+import (
+	"ControlApp/Dmx"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"go.bug.st/serial"
+)
+
+// Prints the channels of the universes sent to an Enttec DMX USB Pro widget.
+// Reads from the serial device given as argument, e.g. the other end of a serial loopback, or stdin.
+func main() {
+	showAll := flag.Bool("all", false, "print every packet instead of only the changed channels")
+	flag.Parse()
+
+	input, err := openInput(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error opening input: %s", err)
+	}
+
+	var previous Dmx.Universe
+	for packet := 1; ; packet++ {
+		universe, err := Dmx.ReadEnttecPacket(input)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Error reading DMX packet: %s", err)
+		}
+
+		var changes []string
+		for i, value := range universe {
+			if *showAll && value != 0 || value != previous[i] {
+				changes = append(changes, fmt.Sprintf("%d=%d", i+1, value))
+			}
+		}
+
+		if len(changes) > 0 || *showAll {
+			fmt.Printf("Packet %d: %s\n", packet, strings.Join(changes, " "))
+		}
+		previous = universe
+	}
+}
+
+func openInput(path string) (io.Reader, error) {
+	if path == "" || path == "-" {
+		return os.Stdin, nil
+	}
+
+	return serial.Open(path, &serial.Mode{BaudRate: 57600})
+}
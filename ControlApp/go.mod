@@ -5,11 +5,9 @@ go 1.24
 require (
 	github.com/stianeikeland/go-rpio/v4 v4.6.0
 	go.bug.st/serial v1.6.4
+	golang.org/x/sys v0.19.0
 	periph.io/x/conn/v3 v3.7.2
 	periph.io/x/host/v3 v3.8.5
 )
 
-require (
-	github.com/creack/goselect v0.1.2 // indirect
-	golang.org/x/sys v0.19.0 // indirect
-)
+require github.com/creack/goselect v0.1.2 // indirect
@@ -2,6 +2,7 @@ package main
 
 import (
 	"ControlApp/Api"
+	"ControlApp/Dmx"
 	"ControlApp/Frontend"
 	"ControlApp/Infrastructure"
 	"ControlApp/Lightshow"
@@ -14,7 +15,8 @@ func main() {
 	log.Println("Starting application...")
 
 	// Initialize hardware
-	hardware, err := Infrastructure.Initialize()
+	fixtureConfig := Dmx.LoadFixtureConfiguration()
+	hardware, err := Infrastructure.Initialize(Infrastructure.LoadHardwareConfiguration(), fixtureConfig)
 	if err != nil {
 		log.Fatalf("Error initializing hardware: %s", err)
 	}
@@ -22,6 +24,7 @@ func main() {
 	// Initialize lighting manager
 	visuals := Lightshow.CreateVisualManager(hardware)
 	hardware.SetAnimationProvider(visuals.GetAnimations())
+	hardware.SetLightingObserver(visuals)
 
 	// Setup static file server
 	fileServer := http.FileServer(http.Dir("Frontend/template/static/"))
@@ -35,6 +38,7 @@ func main() {
 	http.HandleFunc("/overrides", pages.HandleOverridesPage)
 	http.HandleFunc("/palettes", pages.HandlePalettesPage)
 	http.HandleFunc("/animations", pages.HandleAnimationPage)
+	http.HandleFunc("/schedule", pages.HandleSchedulePage)
 
 	// Setup api
 	fixture := Api.Fixture{Data: data}
@@ -42,6 +46,7 @@ func main() {
 	//Handle lighting override endpoints
 	http.HandleFunc("/api/lighting/mode", fixture.HandleSetLightingOverrideAutoApi)
 	http.HandleFunc("/api/lighting/internal-leds", fixture.HandleSetInternalLedsEnabled)
+	http.HandleFunc("/api/lighting/preview", fixture.HandleLightingPreviewApi)
 
 	//Handle screen override endpoints
 	http.HandleFunc("/api/screen/animation", fixture.HandleSetScreenOverrideAnimationSetApi)
@@ -61,6 +66,24 @@ func main() {
 	http.HandleFunc("/api/config/mood", fixture.HandleChangeAutoModeMoodApi)
 	http.HandleFunc("/api/config/nsfw", fixture.HandleChangeAutoModeNsfwApi)
 	http.HandleFunc("/api/config/advanced", fixture.HandleChangeAutoModeConfigApi)
+	http.HandleFunc("/api/auto/state", fixture.HandleAutoModeStateApi)
+	http.HandleFunc("/api/schedule", fixture.HandleScheduleApi)
+
+	//Handle preset endpoints
+	http.HandleFunc("/api/presets", fixture.HandlePresetGetAllApi)
+	http.HandleFunc("/api/preset", fixture.HandleSinglePresetApi)
+	http.HandleFunc("/api/preset/recall", fixture.HandleRecallPresetApi)
+
+	//Handle hardware endpoints
+	http.HandleFunc("/api/hardware/connection", fixture.HandleHardwareConnectionApi)
+	http.HandleFunc("/api/tempo", fixture.HandleTempoApi)
+
+	//Let a lighting desk take over the lighting
+	if fixtureConfig.Input != nil {
+		if err := fixture.StartLightingDeskInput(*fixtureConfig.Input); err != nil {
+			log.Fatalf("Error starting lighting desk input: %s", err)
+		}
+	}
 
 	//Handle other endpoints
 	http.HandleFunc("/api/ping", func(writer http.ResponseWriter, request *http.Request) {})